@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/brutella/modbussy/ui"
@@ -18,18 +19,87 @@ var (
 )
 
 type storage struct {
+	// Datapoints and Modbus are the datapoints and the modbus
+	// configuration of the selected profile. Databases without
+	// profiles store them at the top level.
 	Datapoints []*ui.Datapoint         `json:"datapoints,omitempty"`
 	Modbus     *ui.ModbusConfiguration `json:"modbus,omitempty"`
+
+	Profiles []*ui.Profile `json:"profiles,omitempty"`
+
+	// LastProfile is the name of the most recently used profile.
+	LastProfile string `json:"last-profile,omitempty"`
+
+	// profile is the selected profile.
+	profile *ui.Profile
+
+	// readOnly is true if values must not be written,
+	// because of --read-only or a read-only profile.
+	readOnly bool
+
+	// history logs written values.
+	history *ui.History
+}
+
+// selectProfile makes the profile the selected profile.
+func (stg *storage) selectProfile(p *ui.Profile) {
+	stg.profile = p
+	stg.Datapoints = p.Datapoints
+	stg.Modbus = p.Modbus
+	stg.LastProfile = p.Name
+}
+
+// migrate moves the datapoints and modbus configuration of
+// databases without profiles into a profile named "default".
+// Profiles and connections which were stored without modbus
+// configuration get an empty configuration.
+func (stg *storage) migrate() {
+	stg.Profiles = slices.DeleteFunc(stg.Profiles, func(p *ui.Profile) bool {
+		return p == nil
+	})
+
+	if len(stg.Profiles) == 0 {
+		p := ui.NewProfile("default")
+		if stg.Modbus != nil {
+			p.Modbus = stg.Modbus
+		}
+		if stg.Datapoints != nil {
+			p.Datapoints = stg.Datapoints
+		}
+		stg.Profiles = []*ui.Profile{p}
+		stg.LastProfile = p.Name
+	}
+
+	for _, p := range stg.Profiles {
+		if p.Modbus == nil {
+			p.Modbus = &ui.ModbusConfiguration{}
+		}
+
+		p.Connections = slices.DeleteFunc(p.Connections, func(c *ui.Connection) bool {
+			return c == nil
+		})
+		for _, c := range p.Connections {
+			if c.Modbus == nil {
+				c.Modbus = &ui.ModbusConfiguration{}
+			}
+		}
+	}
 }
 
 func main() {
 	dbFlag := flag.String("db", "~/.modbussy", "Path to database file")
 	transportFlag := flag.String("transport", "", "Transport type (either rtu, tcp, rtuovertcp, or rtuoverudp)")
 	addressFlag := flag.String("address", "", "Address of modbus server")
-	baudRate := flag.Uint("baudrate", 19200, "RTU Baudrate")
-	dataBits := flag.Uint("databits", 8, "RTU Data Bits")
-	parity := flag.String("parity", "E", "RTU Parity; either E(ven), N(one), O(dd)")
-	stopBits := flag.Uint("stopbits", 1, "RTU Stop Bits")
+	baudRate := flag.Uint("baudrate", 0, "RTU Baudrate (e.g. 9600, 19200)")
+	dataBits := flag.Uint("databits", 0, "RTU Data Bits")
+	parity := flag.String("parity", "", "RTU Parity; either E(ven), N(one), O(dd)")
+	stopBits := flag.Uint("stopbits", 0, "RTU Stop Bits")
+	timeout := flag.Duration("timeout", 0, "Request timeout (e.g. 500ms, 2s)")
+	retries := flag.Int("retries", -1, "Number of retries of failed requests")
+	interval := flag.Duration("interval", 0, "Auto reload interval (e.g. 500ms, 1s, 1m)")
+	maxGap := flag.Int("gap", -1, "Maximum number of unused registers between datapoints read in one request")
+	profileFlag := flag.String("profile", "", "Name of the connection profile")
+	readOnly := flag.Bool("read-only", false, "Disable writing values")
 	flag.Parse()
 
 	n := len(os.Args)
@@ -45,14 +115,40 @@ func main() {
 	}
 
 	// Read the stored data
-	stg := storage{
-		Datapoints: []*ui.Datapoint{},
-		Modbus:     &ui.ModbusConfiguration{},
-	}
+	stg := storage{}
 	buf, err := os.ReadFile(dbFilePath)
 	if err == nil {
 		json.Unmarshal(buf, &stg)
 	}
+	stg.migrate()
+	stg.history = ui.NewHistory(dbFilePath + ".history")
+
+	// Select the profile
+	subcommand := flag.Arg(0)
+	profile := ui.FindProfile(stg.Profiles, stg.LastProfile)
+	switch {
+	case len(*profileFlag) > 0:
+		profile = ui.FindProfile(stg.Profiles, *profileFlag)
+		if profile == nil && len(subcommand) > 0 {
+			logError(fmt.Errorf(`profile "%s" not found`, *profileFlag))
+			os.Exit(2)
+		}
+
+		if profile == nil {
+			profile = ui.NewProfile(*profileFlag)
+			stg.Profiles = append(stg.Profiles, profile)
+		}
+	case len(subcommand) == 0:
+		profile, err = ui.PromptProfile(&stg.Profiles, stg.LastProfile)
+		if err != nil {
+			logError(err)
+			os.Exit(1)
+		}
+	case profile == nil:
+		profile = stg.Profiles[0]
+	}
+	stg.selectProfile(profile)
+	stg.readOnly = *readOnly || profile.ReadOnly
 
 	if transportFlag != nil && len(*transportFlag) > 0 {
 		stg.Modbus.Transport = *transportFlag
@@ -61,15 +157,15 @@ func main() {
 	if addressFlag != nil && len(*addressFlag) > 0 {
 		stg.Modbus.Addr = *addressFlag
 	}
-	if baudRate != nil {
+	if baudRate != nil && *baudRate > 0 {
 		stg.Modbus.BaudRate = *baudRate
 	}
 
-	if dataBits != nil {
+	if dataBits != nil && *dataBits > 0 {
 		stg.Modbus.DataBits = *dataBits
 	}
 
-	if parity != nil {
+	if parity != nil && len(*parity) > 0 {
 		switch *parity {
 		case "E":
 			stg.Modbus.Parity = modbus.PARITY_EVEN
@@ -82,10 +178,41 @@ func main() {
 		}
 	}
 
-	if stopBits != nil {
+	if stopBits != nil && *stopBits > 0 {
 		stg.Modbus.StopBits = *stopBits
 	}
 
+	if timeout != nil && *timeout > 0 {
+		stg.Modbus.Timeout = ui.Duration(*timeout)
+	}
+
+	if retries != nil && *retries >= 0 {
+		stg.Modbus.Retries = uint(*retries)
+	}
+
+	if interval != nil && *interval > 0 {
+		stg.Modbus.PollInterval = ui.Duration(*interval)
+	}
+
+	if maxGap != nil && *maxGap >= 0 {
+		stg.Modbus.MaxGap = uint16(*maxGap)
+	}
+
+	switch subcommand {
+	case "read":
+		os.Exit(runRead(stg, flag.Args()[1:]))
+	case "write":
+		os.Exit(runWrite(stg, flag.Args()[1:]))
+	case "watch":
+		os.Exit(runWatch(stg, flag.Args()[1:]))
+	case "scan":
+		os.Exit(runScan(stg, dbFilePath, flag.Args()[1:]))
+	case "units":
+		os.Exit(runUnits(stg, flag.Args()[1:]))
+	case "serve":
+		os.Exit(runServe(stg, dbFilePath, flag.Args()[1:]))
+	}
+
 	for {
 		// Prompt modbus configuration
 		err := ui.PromptConfig(stg.Modbus)
@@ -95,36 +222,87 @@ func main() {
 		}
 
 		// Connect to modbus
-		client, err := modbus.NewClient(stg.Modbus.ClientConfiguration())
-		if err != nil {
-			logError(err)
-			continue
-		}
-		err = client.Open()
+		client, err := connect(stg.Modbus)
 		if err != nil {
 			logError(err)
 			continue
 		}
 
 		// Prompt the data table
-		stg.Datapoints, _ = ui.PromptTable(client, stg.Datapoints)
+		stg.Datapoints, stg.profile.Connections, _ = ui.PromptTable(client, stg.Modbus, stg.Datapoints, stg.profile.Connections, ui.TableOptions{
+			ReadOnly: stg.readOnly,
+			History:  stg.history,
+		})
 		client.Close()
 
 		// Store the returned data
-		buf, err := json.Marshal(stg)
-		if err != nil {
+		if err := save(dbFilePath, stg); err != nil {
 			logError(err)
-		} else {
-			create(dbFilePath)
-			err = os.WriteFile(dbFilePath, buf, 0644)
-			if err != nil {
-				logError(err)
-			}
 		}
 		os.Exit(1)
 	}
 }
 
+// connect returns an open modbus client for the configuration.
+func connect(cfg *ui.ModbusConfiguration) (*modbus.ModbusClient, error) {
+	client, err := modbus.NewClient(cfg.ClientConfiguration())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Open(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// dial returns an open client for the configuration, which retries
+// failed requests the configured number of times, and a function
+// which closes the client.
+func dial(cfg *ui.ModbusConfiguration) (ui.Client, func(), error) {
+	client, err := connect(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, close := ui.NewClient(client, cfg.Retries)
+	return c, close, nil
+}
+
+// connection returns the modbus configuration of the connection
+// with the name, or of the default connection if name is empty.
+func connection(stg storage, name string) (*ui.ModbusConfiguration, error) {
+	if name == "" {
+		return stg.Modbus, nil
+	}
+
+	if c := ui.FindConnection(stg.profile.Connections, name); c != nil {
+		return c.Modbus, nil
+	}
+
+	return nil, fmt.Errorf(`unknown connection "%s"`, name)
+}
+
+// save writes the storage to the database file at p.
+func save(p string, stg storage) error {
+	// Store the datapoints and modbus configuration in the selected profile
+	if stg.profile != nil {
+		stg.profile.Datapoints = stg.Datapoints
+		stg.profile.Modbus = stg.Modbus
+	}
+	stg.Datapoints = nil
+	stg.Modbus = nil
+
+	buf, err := json.Marshal(stg)
+	if err != nil {
+		return err
+	}
+
+	create(p)
+	return os.WriteFile(p, buf, 0644)
+}
+
 func create(p string) (*os.File, error) {
 	if err := os.MkdirAll(filepath.Dir(p), 0770); err != nil {
 		return nil, err
@@ -134,5 +312,5 @@ func create(p string) (*os.File, error) {
 
 // logError renders an error based on the current theme.
 func logError(err error) {
-	fmt.Println(ui.Theme.Focused.ErrorMessage.Render(err.Error()))
+	fmt.Fprintln(os.Stderr, ui.Theme.Focused.ErrorMessage.Render(err.Error()))
 }
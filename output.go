@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// column is a column of printed results. Key is the name of the
+// column in csv output and Title its heading in table output.
+type column struct {
+	Key   string
+	Title string
+}
+
+// newFlagSet returns the flag set of the subcommand with the name.
+// The usage lists the arguments, which follow the flags.
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		usage := fmt.Sprintf("Usage: modbussy [flags] %s [%s-flags]", name, name)
+		if arguments != "" {
+			usage += " " + arguments
+		}
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// formatFlag defines the flag of the output format of printResults.
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "Output format; either table, json or csv")
+}
+
+// checkFormat returns an error if format is not supported by printResults.
+func checkFormat(format string) error {
+	switch format {
+	case "table", "json", "csv":
+		return nil
+	}
+
+	return fmt.Errorf(`invalid format "%s"`, format)
+}
+
+// printResults prints the results in the format. Json output encodes
+// the results; csv and table output print the rows of the columns.
+func printResults(w io.Writer, format string, results any, columns []column, rows [][]string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "csv":
+		keys := make([]string, len(columns))
+		for i, c := range columns {
+			keys[i] = c.Key
+		}
+
+		cw := csv.NewWriter(w)
+		cw.Write(keys)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case "table":
+		titles := make([]string, len(columns))
+		for i, c := range columns {
+			titles[i] = c.Title
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(titles, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+
+	return checkFormat(format)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brutella/modbussy/ui"
+)
+
+// result is the value of a datapoint which is printed to stdout.
+type result struct {
+	Time    string `json:"time,omitempty"`
+	Name    string `json:"name"`
+	SlaveId uint8  `json:"slaveId"`
+	Addr    uint16 `json:"addr"`
+	Space   string `json:"space"`
+	Raw     any    `json:"raw"`
+	Value   any    `json:"value"`
+	Unit    string `json:"unit,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newResult(dp *ui.Datapoint) result {
+	r := result{
+		Name:    dp.Name,
+		SlaveId: dp.SlaveId,
+		Addr:    dp.Addr,
+		Space:   dp.Space.String(),
+		Raw:     jsonValue(dp.Value),
+		Value:   jsonValue(dp.ScaledValue(dp.Value)),
+		Unit:    dp.Unit,
+	}
+
+	if dp.Err != nil {
+		r.Error = dp.Err.Error()
+	}
+
+	return r
+}
+
+// jsonValue returns v as string if it can't be encoded as json number.
+func jsonValue(v any) any {
+	var f float64
+	switch v := v.(type) {
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return v
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%v", f)
+	}
+	return v
+}
+
+// runRead reads the datapoints of the database, or an ad-hoc datapoint,
+// and prints their values to stdout. It returns the exit code.
+func runRead(stg storage, args []string) int {
+	fs := newFlagSet("read", "[datapoint names...]")
+	format := formatFlag(fs)
+	dp, adhoc := datapointFlags(fs)
+	fs.Parse(args)
+
+	if err := checkFormat(*format); err != nil {
+		logError(err)
+		return 2
+	}
+
+	datapoints, err := selectDatapoints(stg.Datapoints, fs.Args())
+	if err == nil && adhoc() {
+		datapoints, err = adhocDatapoint(dp)
+	}
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if len(datapoints) == 0 {
+		logError(errors.New("no datapoints to read"))
+		return 2
+	}
+
+	// Read the datapoints of every connection concurrently
+	byConnection := map[string][]*ui.Datapoint{}
+	for _, dp := range datapoints {
+		byConnection[dp.Connection] = append(byConnection[dp.Connection], dp)
+	}
+
+	var wg sync.WaitGroup
+	for name, dps := range byConnection {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			readConnection(stg, name, dps)
+		}()
+	}
+	wg.Wait()
+
+	results := make([]result, len(datapoints))
+	rows := make([][]string, len(datapoints))
+	for i, dp := range datapoints {
+		results[i] = newResult(dp)
+		rows[i] = results[i].strings()
+	}
+
+	if err := printResults(os.Stdout, *format, results, resultColumns, rows); err != nil {
+		logError(err)
+		return 2
+	}
+
+	for _, dp := range datapoints {
+		if dp.Err != nil {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// readConnection reads the datapoints of the connection with the
+// name. If connecting fails, the error is set for every datapoint.
+func readConnection(stg storage, name string, datapoints []*ui.Datapoint) {
+	cfg, err := connection(stg, name)
+	if err == nil {
+		var client ui.Client
+		var close func()
+		if client, close, err = dial(cfg); err == nil {
+			defer close()
+			ui.ReadDatapoints(client, datapoints, cfg.MaxGap)
+			return
+		}
+	}
+
+	for _, dp := range datapoints {
+		dp.Err = err
+	}
+}
+
+// adhocFlags are the flags which specify an ad-hoc datapoint.
+type adhocFlags struct {
+	slave *uint
+	addr  *int
+	typ   *string
+	space *string
+	order *string
+}
+
+// datapointFlags defines the flags to specify an ad-hoc datapoint.
+// The returned function returns true if an ad-hoc datapoint was specified.
+func datapointFlags(fs *flag.FlagSet) (*adhocFlags, func() bool) {
+	f := &adhocFlags{
+		slave: fs.Uint("slave", 1, "Server ID of an ad-hoc datapoint"),
+		addr:  fs.Int("addr", -1, "Address of an ad-hoc datapoint"),
+		typ:   fs.String("type", "uint16", "Data type of an ad-hoc datapoint (e.g. uint16, int32, float32, bool, string8 for 8 registers, or bits4-7)"),
+		space: fs.String("space", "hr", "Register space of an ad-hoc datapoint; either hr, ir, coil or di"),
+		order: fs.String("order", "ABCD", "Byte order of an ad-hoc datapoint; either ABCD, DCBA, BADC or CDAB"),
+	}
+
+	return f, func() bool {
+		return *f.addr >= 0
+	}
+}
+
+// adhocDatapoint returns the datapoint specified by the flags.
+func adhocDatapoint(f *adhocFlags) ([]*ui.Datapoint, error) {
+	dp, err := newDatapoint(*f.slave, *f.addr, *f.typ, *f.space, *f.order)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*ui.Datapoint{dp}, nil
+}
+
+// newDatapoint returns a datapoint with the server id, address,
+// data type, register space and byte order.
+func newDatapoint(slave uint, addr int, typ, space, order string) (*ui.Datapoint, error) {
+	if addr < 0 || addr > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid address %d", addr)
+	}
+
+	if slave > math.MaxUint8 {
+		return nil, fmt.Errorf("invalid server id %d", slave)
+	}
+
+	// Strings specify their number of registers, e.g. string8
+	var length uint64
+	if n, ok := strings.CutPrefix(strings.ToLower(typ), "string"); ok && len(n) > 0 {
+		var err error
+		if length, err = strconv.ParseUint(n, 10, 16); err != nil || length == 0 {
+			return nil, fmt.Errorf(`invalid string length "%s"`, n)
+		}
+		typ = "string"
+	}
+
+	// Bit-fields specify their bits, e.g. bit3 or bits4-7
+	var mask ui.BitMask
+	rest, ok := strings.CutPrefix(strings.ToLower(typ), "bits")
+	if !ok {
+		rest, ok = strings.CutPrefix(strings.ToLower(typ), "bit")
+	}
+	if ok && len(rest) > 0 {
+		var err error
+		if mask, err = ui.ParseBitMask(rest); err != nil {
+			return nil, err
+		}
+		typ = "bits"
+	}
+
+	dt, err := ui.ParseDataType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := ui.ParseSpace(space)
+	if err != nil {
+		return nil, err
+	}
+
+	bo, err := ui.ParseByteOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	dp := &ui.Datapoint{
+		SlaveId:   uint8(slave),
+		Addr:      uint16(addr),
+		Space:     sp,
+		DataType:  dt,
+		ByteOrder: bo,
+		Length:    uint16(length),
+		Mask:      mask,
+	}
+
+	return dp, nil
+}
+
+// selectDatapoints returns the datapoints with the names.
+// If no names are specified, all datapoints are returned.
+func selectDatapoints(datapoints []*ui.Datapoint, names []string) ([]*ui.Datapoint, error) {
+	if len(names) == 0 {
+		return datapoints, nil
+	}
+
+	var selected []*ui.Datapoint
+	for _, name := range names {
+		dp := findDatapoint(datapoints, name)
+		if dp == nil {
+			return nil, fmt.Errorf(`datapoint "%s" not found`, name)
+		}
+		selected = append(selected, dp)
+	}
+
+	return selected, nil
+}
+
+// findDatapoint returns the datapoint with the name.
+func findDatapoint(datapoints []*ui.Datapoint, name string) *ui.Datapoint {
+	for _, dp := range datapoints {
+		if dp.Name == name {
+			return dp
+		}
+	}
+	return nil
+}
+
+// resultColumns are the columns of printed results.
+var resultColumns = []column{
+	{"name", "NAME"},
+	{"slaveId", "SERVER ID"},
+	{"addr", "ADDRESS"},
+	{"space", "SPACE"},
+	{"raw", "RAW"},
+	{"value", "VALUE"},
+	{"unit", "UNIT"},
+	{"error", "ERROR"},
+}
+
+// strings returns the fields of the result as strings.
+func (r result) strings() []string {
+	fmtValue := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprintf("%v", v)
+	}
+
+	return []string{
+		r.Name,
+		fmt.Sprintf("%d", r.SlaveId),
+		fmt.Sprintf("%d", r.Addr),
+		r.Space,
+		fmtValue(r.Raw),
+		fmtValue(r.Value),
+		r.Unit,
+		r.Error,
+	}
+}
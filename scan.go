@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/brutella/modbussy/ui"
+)
+
+// scanResult is the printed result of a scanned address.
+type scanResult struct {
+	SlaveId uint8  `json:"slaveId"`
+	Addr    uint16 `json:"addr"`
+	Space   string `json:"space"`
+	Raw     uint16 `json:"raw"`
+	Error   string `json:"error,omitempty"`
+}
+
+// scanColumns are the columns of printed scan results.
+var scanColumns = []column{
+	{"slaveId", "SERVER ID"},
+	{"addr", "ADDRESS"},
+	{"space", "SPACE"},
+	{"raw", "RAW"},
+	{"error", "ERROR"},
+}
+
+func (r scanResult) strings() []string {
+	raw := ""
+	if r.Error == "" {
+		raw = fmt.Sprintf("%d", r.Raw)
+	}
+	return []string{fmt.Sprintf("%d", r.SlaveId), fmt.Sprintf("%d", r.Addr), r.Space, raw, r.Error}
+}
+
+// runScan reads a range of addresses and prints which addresses
+// respond. Responding addresses can be saved as datapoints to the
+// database at dbFilePath. It returns the exit code.
+func runScan(stg storage, dbFilePath string, args []string) int {
+	fs := newFlagSet("scan", "")
+	slave := fs.Uint("slave", 1, "Server ID to scan")
+	space := fs.String("space", "hr", "Register space to scan; either hr, ir, coil or di")
+	addrs := fs.String("range", "0-99", "Range of addresses to scan (e.g. 0-999)")
+	format := formatFlag(fs)
+	all := fs.Bool("all", false, "Also print addresses which returned an illegal data address exception")
+	persist := fs.Bool("save", false, "Add responding addresses as datapoints to the database")
+	fs.Parse(args)
+
+	if *slave > math.MaxUint8 {
+		logError(fmt.Errorf("invalid server id %d", *slave))
+		return 2
+	}
+
+	sp, err := ui.ParseSpace(*space)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	start, end, err := parseRange(*addrs)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if err := checkFormat(*format); err != nil {
+		logError(err)
+		return 2
+	}
+
+	client, close, err := dial(stg.Modbus)
+	if err != nil {
+		logError(err)
+		return 1
+	}
+	defer close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var results []scanResult
+	var rows [][]string
+	var hits []ui.ScanResult
+	var illegal, failed int
+	rng := ui.ScanRange{SlaveId: uint8(*slave), Space: sp, Start: start, End: end}
+	ui.Scan(ctx, client, rng, func(res ui.ScanResult) {
+		switch {
+		case res.IsIllegalAddress():
+			illegal++
+			if !*all {
+				return
+			}
+		case res.Err != nil:
+			failed++
+		default:
+			hits = append(hits, res)
+		}
+
+		r := scanResult{SlaveId: res.SlaveId, Addr: res.Addr, Space: res.Space.String(), Raw: res.Value}
+		if res.Err != nil {
+			r.Error = res.Err.Error()
+		}
+		results = append(results, r)
+		rows = append(rows, r.strings())
+	})
+
+	if err := printResults(os.Stdout, *format, results, scanColumns, rows); err != nil {
+		logError(err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "%d responding, %d illegal, %d failed\n", len(hits), illegal, failed)
+
+	if *persist && len(hits) > 0 {
+		for _, hit := range hits {
+			stg.Datapoints = append(stg.Datapoints, hit.Datapoint())
+		}
+
+		if err := save(dbFilePath, stg); err != nil {
+			logError(err)
+			return 1
+		}
+	}
+
+	if ctx.Err() != nil || failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// parseRange parses a range of addresses (e.g. "0-999").
+func parseRange(s string) (start, end uint16, err error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		to = from
+	}
+
+	a, errA := strconv.ParseUint(from, 0, 16)
+	b, errB := strconv.ParseUint(to, 0, 16)
+	if errA != nil || errB != nil || b < a {
+		return 0, 0, fmt.Errorf(`invalid address range "%s"`, s)
+	}
+
+	return uint16(a), uint16(b), nil
+}
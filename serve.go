@@ -0,0 +1,36 @@
+package main
+
+import "github.com/brutella/modbussy/ui"
+
+// runServe serves the datapoints as a modbus server and shows them
+// in the table, until the user quits. Changes to the datapoints are
+// stored at dbFilePath. It returns the exit code.
+func runServe(stg storage, dbFilePath string, args []string) int {
+	fs := newFlagSet("serve", "")
+	listen := fs.String("listen", "tcp://localhost:5502", "URL to listen at (tcp://host:port or rtuovertcp://host:port)")
+	fs.Parse(args)
+
+	sim, err := ui.NewSimulator(stg.Datapoints)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if err := sim.Serve(*listen); err != nil {
+		logError(err)
+		return 1
+	}
+	defer sim.Stop()
+
+	stg.Datapoints, err = ui.PromptSimulator(sim, *listen, stg.Datapoints, stg.readOnly)
+	if err != nil {
+		logError(err)
+	}
+
+	if err := save(dbFilePath, stg); err != nil {
+		logError(err)
+		return 1
+	}
+
+	return 0
+}
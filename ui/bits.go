@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/xiam/to"
+)
+
+// BitMask selects the bits of a register which store
+// the value of a bit-field datapoint.
+type BitMask uint16
+
+// ParseBitMask returns the bit mask specified as bit index
+// (e.g. "3"), range of bits (e.g. "4-7") or hexadecimal mask
+// (e.g. "0x00F0").
+func ParseBitMask(s string) (BitMask, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		m, err := strconv.ParseUint(s[2:], 16, 16)
+		if err != nil || m == 0 {
+			return 0, fmt.Errorf(`invalid bit mask "%s"`, s)
+		}
+		return BitMask(m), nil
+	}
+
+	first, last, found := strings.Cut(s, "-")
+	if !found {
+		last = first
+	}
+
+	lo, err := strconv.ParseUint(first, 10, 8)
+	if err != nil || lo > 15 {
+		return 0, fmt.Errorf(`invalid bit "%s"`, first)
+	}
+
+	hi, err := strconv.ParseUint(last, 10, 8)
+	if err != nil || hi > 15 || hi < lo {
+		return 0, fmt.Errorf(`invalid bit "%s"`, last)
+	}
+
+	return BitMask((1<<(hi+1) - 1) &^ (1<<lo - 1)), nil
+}
+
+// String returns the mask as bit index, range of bits
+// or, if the bits are not contiguous, as hexadecimal mask.
+func (m BitMask) String() string {
+	m = m.orDefault()
+	lo := m.shift()
+	hi := 15 - bits.LeadingZeros16(uint16(m))
+	switch {
+	case m.single():
+		return fmt.Sprintf("%d", lo)
+	case bits.OnesCount16(uint16(m)) == hi-lo+1:
+		return fmt.Sprintf("%d-%d", lo, hi)
+	}
+
+	return fmt.Sprintf("0x%04X", uint16(m))
+}
+
+// orDefault returns the mask, or bit 0 if no bits are selected.
+func (m BitMask) orDefault() BitMask {
+	if m == 0 {
+		return 1
+	}
+	return m
+}
+
+// shift returns the index of the lowest selected bit.
+func (m BitMask) shift() int {
+	return bits.TrailingZeros16(uint16(m.orDefault()))
+}
+
+// single returns true if the mask selects a single bit.
+func (m BitMask) single() bool {
+	return bits.OnesCount16(uint16(m.orDefault())) == 1
+}
+
+// max returns the value of the bit-field with all bits set.
+func (m BitMask) max() uint16 {
+	return uint16(m.orDefault()) >> m.shift()
+}
+
+// decodeBits returns the value of the bit-field in the register,
+// which is a bool for single bits.
+func (c codec) decodeBits(reg uint16) any {
+	v := (reg & uint16(c.Mask.orDefault())) >> c.Mask.shift()
+	if c.Mask.single() {
+		return v != 0
+	}
+	return v
+}
+
+// encodeBits returns the value at the position of the bit-field.
+// The other bits are zero.
+func (c codec) encodeBits(val any) uint16 {
+	var v uint16
+	if c.Mask.single() {
+		if to.Bool(val) {
+			v = 1
+		}
+	} else {
+		v = uint16(to.Uint64(val))
+	}
+
+	return v << c.Mask.shift() & uint16(c.Mask.orDefault())
+}
+
+// mergeBits returns the stored register with the bit-field set to val.
+// Like decodeRegisters, the bit-field refers to the register in the
+// byte order of the codec, which is reordered back after merging.
+func (c codec) mergeBits(reg uint16, val any) uint16 {
+	reg = c.ByteOrder.reorder([]uint16{reg})[0]
+	reg = reg&^uint16(c.Mask.orDefault()) | c.encodeBits(val)
+
+	return c.ByteOrder.reorder([]uint16{reg})[0]
+}
+
+// BitMaskAccessor accesses a bit mask as a string.
+type BitMaskAccessor struct {
+	val *BitMask
+}
+
+func NewBitMaskAccessor(val *BitMask) *BitMaskAccessor {
+	return &BitMaskAccessor{val}
+}
+
+func (a *BitMaskAccessor) Get() string {
+	return a.val.String()
+}
+
+func (a *BitMaskAccessor) Set(value string) {
+	m, err := ParseBitMask(value)
+	if err == nil {
+		*a.val = m
+	}
+}
+
+// validateBitMask validates a bit mask.
+func validateBitMask(s string) error {
+	_, err := ParseBitMask(s)
+	return err
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/huh"
 	"github.com/simonvetter/modbus"
@@ -16,6 +17,20 @@ type ModbusConfiguration struct {
 	DataBits  uint   `json:"databits,omitempty"`
 	Parity    uint   `json:"parity,omitempty"`
 	StopBits  uint   `json:"stopbits,omitempty"`
+
+	// MaxGap is the maximum number of unused registers between
+	// datapoints which are read with a single request.
+	MaxGap uint16 `json:"max-gap,omitempty"`
+
+	// PollInterval is the auto reload interval.
+	PollInterval Duration `json:"poll-interval,omitempty"`
+
+	// Timeout is the request timeout. If zero, the
+	// default timeout of the transport is used.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// Retries is the number of times a failed request is retried.
+	Retries uint `json:"retries,omitempty"`
 }
 
 func (c *ModbusConfiguration) ClientConfiguration() *modbus.ClientConfiguration {
@@ -25,16 +40,36 @@ func (c *ModbusConfiguration) ClientConfiguration() *modbus.ClientConfiguration
 	cfg.StopBits = c.StopBits
 	cfg.Parity = c.Parity
 	cfg.Speed = c.BaudRate
+	cfg.Timeout = time.Duration(c.Timeout)
 
 	return cfg
 }
 
+// Description returns a short description of
+// the connection (e.g. "tcp://localhost:502").
+func (c ModbusConfiguration) Description() string {
+	if c.Transport == "" {
+		return "not configured"
+	}
+
+	s := fmt.Sprintf("%s://%s", c.Transport, c.Addr)
+	if c.Transport == "rtu" {
+		s += " " + c.SerialSettings()
+	}
+
+	return s
+}
+
 // PromptConfig prompts to the user to configurate
 // the modbus connection.
 func PromptConfig(cfg *ModbusConfiguration) error {
 	addressInput := huh.NewInput()
 	addressInput.Skip()
 	stopBitsInput := newIntInput(1, 2)
+	parity := cfg.Parity
+
+	detect := false
+	detectUnitId := uint8(1)
 
 	err := huh.NewForm(
 		huh.NewGroup(
@@ -52,7 +87,7 @@ func PromptConfig(cfg *ModbusConfiguration) error {
 					case "tcp", "rtuovertcp", "rtuoverudp":
 						cfg.Addr = "localhost:502"
 					default:
-						cfg.Addr = "/dev/ttyUSB0"
+						cfg.Addr = defaultSerialPort()
 					}
 
 					// call updated to update the input value
@@ -73,16 +108,60 @@ func PromptConfig(cfg *ModbusConfiguration) error {
 					case "tcp", "rtuovertcp", "rtuoverudp":
 						return []string{"localhost:502"}
 					case "rtu":
+						if ports := SerialPorts(); len(ports) > 0 {
+							return ports
+						}
 						return []string{"/dev/ttyUSB0"}
 					}
 					return []string{}
 				}, &cfg.Transport).
 				Value(&cfg.Addr),
 		),
+		huh.NewGroup(
+			huh.NewSelect[bool]().
+				Title("Serial settings").
+				Options(
+					huh.NewOption("Enter manually", false),
+					huh.NewOption("Auto-detect", true),
+				).
+				Value(&detect),
+		).
+			WithHideFunc(func() bool {
+				return cfg.Transport != "rtu"
+			}),
+		huh.NewGroup(
+			newIntInput(1, 247).
+				Title("Enter the server id to detect the serial settings with").
+				Accessor(NewNumberAccessor(&detectUnitId)),
+		).
+			WithHideFunc(func() bool {
+				return cfg.Transport != "rtu" || !detect
+			}),
+	).Run()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Transport == "rtu" && detect {
+		err := DetectSerial(cfg, detectUnitId, func(c ModbusConfiguration) {
+			fmt.Printf("Trying %s…\n", c.SerialSettings())
+		})
+		if err != nil {
+			fmt.Println(Theme.Focused.ErrorMessage.Render(err.Error()))
+		} else {
+			fmt.Printf("Server %d responded with %s\n", detectUnitId, cfg.SerialSettings())
+		}
+		parity = cfg.Parity
+	}
+
+	return huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[uint]().
 				Title("Select a baud rate").
 				Options(
+					huh.NewOption("115.200 kBit/s", uint(115_200)),
+					huh.NewOption("57.600 kBit/s", uint(57_600)),
+					huh.NewOption("38.400 kBit/s", uint(38_400)),
 					huh.NewOption("19.200 kBit/s", uint(19_200)),
 					huh.NewOption("9.600 kBit/s", uint(9600)),
 					huh.NewOption("4.800 kBit/s", uint(4800)),
@@ -101,6 +180,13 @@ func PromptConfig(cfg *ModbusConfiguration) error {
 					huh.NewOption("Odd", modbus.PARITY_ODD),
 				).
 				Validate(func(val uint) error {
+					// Only suggest stop bits if the parity changed,
+					// so that detected settings are kept
+					if val == parity {
+						return nil
+					}
+					parity = val
+
 					switch val {
 					case modbus.PARITY_NONE:
 						cfg.StopBits = 2
@@ -123,6 +209,25 @@ func PromptConfig(cfg *ModbusConfiguration) error {
 			WithHideFunc(func() bool {
 				return cfg.Transport != "rtu"
 			}),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Enter the request timeout").
+				Placeholder("default (e.g. 500ms, 2s)").
+				Validate(validateDuration).
+				Accessor(NewDurationAccessor(&cfg.Timeout)),
+
+			newIntInput(0, 10).
+				Title("Enter the number of retries").
+				Accessor(NewNumberAccessor(&cfg.Retries)),
+		).
+			Title("Connection"),
 	).Run()
-	return err
+}
+
+// defaultSerialPort returns the first available serial device.
+func defaultSerialPort() string {
+	if ports := SerialPorts(); len(ports) > 0 {
+		return ports[0]
+	}
+	return "/dev/ttyUSB0"
 }
@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"errors"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+const (
+	// minBackoff and maxBackoff are the minimum and
+	// maximum duration between reconnect attempts.
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+
+	// maxTimeouts is the number of consecutive timeouts after
+	// which the connection is considered broken, e.g. because
+	// a TCP gateway disappeared without closing the connection.
+	maxTimeouts = 3
+)
+
+// errNotConnected is returned for requests of
+// connections which couldn't be opened.
+var errNotConnected = errors.New("not connected")
+
+// Client is the subset of modbus client functions
+// which are used to read and write datapoints.
+type Client interface {
+	SetUnitId(id uint8) error
+	ReadCoils(addr uint16, quantity uint16) ([]bool, error)
+	ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error)
+	ReadRegisters(addr uint16, quantity uint16, regType modbus.RegType) ([]uint16, error)
+	WriteCoil(addr uint16, value bool) error
+	WriteRegister(addr uint16, value uint16) error
+	WriteRegisters(addr uint16, values []uint16) error
+}
+
+// LinkState represents the state of a connection.
+type LinkState byte
+
+const (
+	LinkConnected LinkState = iota
+	LinkReconnecting
+	LinkServing
+)
+
+// LinkStateMsg is sent when the state of a connection changes.
+type LinkStateMsg struct {
+	State LinkState
+
+	// Err is the error which caused a reconnect.
+	Err error
+
+	// Attempt is the number of the reconnect attempt.
+	Attempt int
+
+	// Connection is the name of the connection,
+	// or empty for the default connection.
+	Connection string
+}
+
+// connection is a modbus client which retries failed requests
+// and reconnects with backoff when the connection is broken.
+type connection struct {
+	*modbus.ModbusClient
+
+	retries uint
+	notify  func(LinkStateMsg)
+
+	// closed is true if the client is not open,
+	// e.g. because it wasn't opened yet.
+	closed bool
+
+	// timeouts is the number of consecutive timeouts.
+	timeouts int
+
+	// attempt is the number of failed attempts to open the
+	// client, which is retried after backoff at retryAt.
+	attempt int
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// NewClient returns a client which sends requests with the open
+// client. Failed requests are retried the given number of times
+// and the client is reopened if the connection is broken. The
+// returned function closes the client.
+func NewClient(client *modbus.ModbusClient, retries uint) (Client, func()) {
+	c := &connection{
+		ModbusClient: client,
+		retries:      retries,
+		notify:       func(LinkStateMsg) {},
+	}
+
+	return c, c.close
+}
+
+// close closes the client unless it is closed already.
+func (c *connection) close() {
+	if !c.closed {
+		c.ModbusClient.Close()
+		c.closed = true
+	}
+}
+
+func (c *connection) ReadCoils(addr uint16, quantity uint16) (values []bool, err error) {
+	err = c.do(func() error {
+		values, err = c.ModbusClient.ReadCoils(addr, quantity)
+		return err
+	})
+	return
+}
+
+func (c *connection) ReadDiscreteInputs(addr uint16, quantity uint16) (values []bool, err error) {
+	err = c.do(func() error {
+		values, err = c.ModbusClient.ReadDiscreteInputs(addr, quantity)
+		return err
+	})
+	return
+}
+
+func (c *connection) ReadRegisters(addr uint16, quantity uint16, regType modbus.RegType) (values []uint16, err error) {
+	err = c.do(func() error {
+		values, err = c.ModbusClient.ReadRegisters(addr, quantity, regType)
+		return err
+	})
+	return
+}
+
+func (c *connection) WriteCoil(addr uint16, value bool) error {
+	return c.do(func() error {
+		return c.ModbusClient.WriteCoil(addr, value)
+	})
+}
+
+func (c *connection) WriteRegister(addr uint16, value uint16) error {
+	return c.do(func() error {
+		return c.ModbusClient.WriteRegister(addr, value)
+	})
+}
+
+func (c *connection) WriteRegisters(addr uint16, values []uint16) error {
+	return c.do(func() error {
+		return c.ModbusClient.WriteRegisters(addr, values)
+	})
+}
+
+// do performs a request and retries it if it failed for other
+// reasons than a modbus exception. If the connection is broken,
+// the client is reopened. While the client can't be reopened,
+// requests fail immediately with errNotConnected, so that the
+// poller isn't blocked; reopening is attempted with backoff.
+func (c *connection) do(req func() error) error {
+	if c.closed && !c.reopen() {
+		return errNotConnected
+	}
+
+	var err error
+	for attempt := uint(0); attempt <= c.retries; attempt++ {
+		err = req()
+		if err == nil || IsException(err) {
+			c.timeouts = 0
+			return err
+		}
+
+		if c.isBroken(err) {
+			c.close()
+			if !c.reopen() {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+// isBroken returns true if the connection is broken because
+// of the error, or because of too many consecutive timeouts.
+func (c *connection) isBroken(err error) bool {
+	if errors.Is(err, modbus.ErrRequestTimedOut) {
+		c.timeouts++
+		return c.timeouts >= maxTimeouts
+	}
+
+	return isConnectionError(err)
+}
+
+// reopen opens the closed client, unless the backoff since the last
+// failed attempt didn't elapse yet. The duration between attempts
+// doubles up to maxBackoff. It returns true if the client is open.
+func (c *connection) reopen() bool {
+	if time.Now().Before(c.retryAt) {
+		return false
+	}
+
+	if err := c.ModbusClient.Open(); err != nil {
+		c.attempt++
+		c.backoff = min(max(c.backoff*2, minBackoff), maxBackoff)
+		c.retryAt = time.Now().Add(c.backoff)
+		c.notify(LinkStateMsg{State: LinkReconnecting, Err: err, Attempt: c.attempt})
+		return false
+	}
+
+	if c.attempt > 0 {
+		c.notify(LinkStateMsg{State: LinkConnected})
+	}
+
+	c.closed = false
+	c.timeouts = 0
+	c.attempt = 0
+	c.backoff = 0
+	c.retryAt = time.Time{}
+
+	return true
+}
+
+// suspend closes the connection while fn is called and reopens it
+// afterwards, so that fn can open another client of the device,
+// e.g. with a different timeout. This is required because the
+// timeout of a client can't be changed, and serial ports can only
+// be opened once.
+func (c *connection) suspend(fn func() error) error {
+	c.close()
+	defer c.reopen()
+
+	return fn()
+}
+
+// IsException returns true if err is an exception
+// returned by the modbus server.
+func IsException(err error) bool {
+	switch err {
+	case modbus.ErrIllegalFunction,
+		modbus.ErrIllegalDataAddress,
+		modbus.ErrIllegalDataValue,
+		modbus.ErrServerDeviceFailure,
+		modbus.ErrAcknowledge,
+		modbus.ErrServerDeviceBusy,
+		modbus.ErrMemoryParityError,
+		modbus.ErrGWPathUnavailable,
+		modbus.ErrGWTargetFailedToRespond:
+		return true
+	}
+
+	return false
+}
+
+// isConnectionError returns true if err is not a modbus
+// error, e.g. because the connection was closed.
+func isConnectionError(err error) bool {
+	var mbErr modbus.Error
+	return !errors.As(err, &mbErr)
+}
@@ -1,10 +1,12 @@
 package ui
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/huh"
@@ -23,10 +25,133 @@ const (
 	DataTypeFloat32
 	DataTypeFloat64
 	DataTypeBool
-	DataTypeCoil
+	DataTypeCoil // Deprecated: use DataTypeBool with SpaceCoil
+	DataTypeInt16
+	DataTypeInt32
+	DataTypeInt64
+	DataTypeString
+	DataTypeBits
 )
 
-// Flag represents a flag.
+var dataTypeNames = map[DataType]string{
+	DataTypeUint16:  "uint16",
+	DataTypeUint32:  "uint32",
+	DataTypeUint64:  "uint64",
+	DataTypeFloat32: "float32",
+	DataTypeFloat64: "float64",
+	DataTypeBool:    "bool",
+	DataTypeCoil:    "coil",
+	DataTypeInt16:   "int16",
+	DataTypeInt32:   "int32",
+	DataTypeInt64:   "int64",
+	DataTypeString:  "string",
+	DataTypeBits:    "bits",
+}
+
+func (dt DataType) String() string {
+	if name, ok := dataTypeNames[dt]; ok {
+		return name
+	}
+	return "?"
+}
+
+// ParseDataType returns the data type with the name (e.g. "int16").
+func ParseDataType(s string) (DataType, error) {
+	for dt, name := range dataTypeNames {
+		if strings.EqualFold(s, name) {
+			return dt, nil
+		}
+	}
+	return 0, fmt.Errorf(`invalid data type "%s"`, s)
+}
+
+// ByteOrder represents the order of the bytes of a value
+// stored in one or more registers, where A is the most
+// significant byte.
+type ByteOrder byte
+
+const (
+	ByteOrderABCD ByteOrder = iota // big endian, high word first
+	ByteOrderDCBA                  // little endian, low word first
+	ByteOrderBADC                  // bytes swapped, high word first
+	ByteOrderCDAB                  // words swapped
+)
+
+func (o ByteOrder) String() string {
+	switch o {
+	case ByteOrderABCD:
+		return "ABCD"
+	case ByteOrderDCBA:
+		return "DCBA"
+	case ByteOrderBADC:
+		return "BADC"
+	case ByteOrderCDAB:
+		return "CDAB"
+	}
+	return "?"
+}
+
+// ParseByteOrder returns the byte order with the name (e.g. "CDAB").
+func ParseByteOrder(s string) (ByteOrder, error) {
+	for _, o := range []ByteOrder{ByteOrderABCD, ByteOrderDCBA, ByteOrderBADC, ByteOrderCDAB} {
+		if strings.EqualFold(s, o.String()) {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf(`invalid byte order "%s"`, s)
+}
+
+// Space represents the register space of a datapoint.
+type Space byte
+
+const (
+	SpaceHoldingRegister Space = iota
+	SpaceInputRegister
+	SpaceCoil
+	SpaceDiscreteInput
+)
+
+// IsBit returns true if the space contains single bit values.
+func (s Space) IsBit() bool {
+	return s == SpaceCoil || s == SpaceDiscreteInput
+}
+
+// IsWritable returns true if clients can write to the space.
+func (s Space) IsWritable() bool {
+	return s == SpaceHoldingRegister || s == SpaceCoil
+}
+
+func (s Space) String() string {
+	switch s {
+	case SpaceHoldingRegister:
+		return "HR"
+	case SpaceInputRegister:
+		return "IR"
+	case SpaceCoil:
+		return "Coil"
+	case SpaceDiscreteInput:
+		return "DI"
+	}
+	return "?"
+}
+
+// ParseSpace returns the register space with the name,
+// which is either hr, ir, coil or di.
+func ParseSpace(s string) (Space, error) {
+	switch strings.ToLower(s) {
+	case "hr", "holding":
+		return SpaceHoldingRegister, nil
+	case "ir", "input":
+		return SpaceInputRegister, nil
+	case "coil":
+		return SpaceCoil, nil
+	case "di", "discrete":
+		return SpaceDiscreteInput, nil
+	}
+	return 0, fmt.Errorf(`invalid register space "%s"`, s)
+}
+
+// Flag represents the access flag of a datapoint.
 type Flag byte
 
 const (
@@ -37,57 +162,191 @@ const (
 // Datapoint represents a value from a modbus server.
 // It specifies the datatype, flags and unit.
 type Datapoint struct {
+	// Connection is the name of the connection of the datapoint.
+	// If empty, the default connection is used.
+	Connection string `json:"connection,omitempty"`
+
 	SlaveId     uint8    `json:"slaveId"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Addr        uint16   `json:"addr"`
+	Space       Space    `json:"space"`
 	DataType    DataType `json:"data-type"`
 	Flag        Flag     `json:"flag"`
 	Unit        string   `json:"unit"`
 
+	// ByteOrder specifies the byte and word order of
+	// the registers. Defaults to big endian (ABCD).
+	// For strings, it specifies the order of the characters,
+	// e.g. BADC if the low byte holds the first character.
+	ByteOrder ByteOrder `json:"byte-order"`
+
+	// Length is the number of registers of a string,
+	// which holds two characters per register.
+	Length uint16 `json:"length,omitempty"`
+
+	// Mask selects the bits of a bit-field, whose value is
+	// a bool for single bits. Defaults to bit 0.
+	Mask BitMask `json:"mask,omitempty"`
+
+	// Labels maps raw values to labels, e.g. 3 to "Boost".
+	Labels map[int64]string `json:"labels,omitempty"`
+
+	// BitLabels maps the bits of raw values to labels, which
+	// are shown for the set bits of values without a label.
+	BitLabels map[uint8]string `json:"bit-labels,omitempty"`
+
+	// MinWrite and MaxWrite limit the values which can be
+	// written, in scaled units. Empty limits don't apply.
+	MinWrite string `json:"min-write,omitempty"`
+	MaxWrite string `json:"max-write,omitempty"`
+
+	// Confirm requires writes to be confirmed a second time.
+	Confirm bool `json:"confirm,omitempty"`
+
+	// PulseDuration is how long the datapoint is switched
+	// on when it's pulsed. Defaults to 1 second.
+	PulseDuration Duration `json:"pulse-duration,omitempty"`
+
+	// PollInterval specifies how often the datapoint is read
+	// when auto reloading. If zero, the datapoint is read with
+	// the auto reload interval of the table.
+	PollInterval Duration `json:"poll-interval,omitempty"`
+
 	// Scaling specifies a scaling of the datapoint value.
-	// For example if value can be negative, we have to scale
-	// the unsigned value to be negative.
+	// For example a register value of 0-1000 can be scaled
+	// to 0-100.0 to get the value in percent.
 	Scaling *Scaling `json:"scaling"`
 
+	// Generator produces simulated values
+	// when serving the datapoint.
+	Generator *Generator `json:"generator,omitempty"`
+
 	// Value is the last read value.
 	Value any `json:"-"`
 
 	// Err is not-nil if the last read failed.
 	Err error `json:"-"`
+
+	// Reading is true while the datapoint is being read.
+	Reading bool `json:"-"`
+
+	// Written is the last written value, if the value which
+	// was read back differs from it.
+	Written any `json:"-"`
+
+	// lastRead is the time when the datapoint was last read.
+	lastRead time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Datapoints stored without a register space are migrated
+// based on their data type and flag.
+func (dp *Datapoint) UnmarshalJSON(data []byte) error {
+	type datapoint Datapoint
+	v := struct {
+		*datapoint
+		Space *Space `json:"space"`
+	}{
+		datapoint: (*datapoint)(dp),
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Space != nil {
+		dp.Space = *v.Space
+		return nil
+	}
+
+	switch {
+	case dp.DataType == DataTypeCoil:
+		dp.Space = SpaceCoil
+		dp.DataType = DataTypeBool
+		dp.Flag = FlagReadWrite
+	case dp.DataType == DataTypeBool:
+		dp.Space = SpaceDiscreteInput
+	case dp.Flag == FlagReadWrite:
+		dp.Space = SpaceHoldingRegister
+	default:
+		dp.Space = SpaceInputRegister
+	}
+
+	return nil
 }
 
 func (dp Datapoint) RegType() modbus.RegType {
-	switch dp.Flag {
-	case FlagRead:
+	return dp.Space.regType()
+}
+
+// regType returns the register type of the space.
+func (s Space) regType() modbus.RegType {
+	switch s {
+	case SpaceInputRegister:
 		return modbus.INPUT_REGISTER
-	case FlagReadWrite:
+	case SpaceHoldingRegister:
 		return modbus.HOLDING_REGISTER
 	}
 
 	return math.MaxUint
 }
 
+// ScaledValue returns the value scaled by the scaling of the
+// datapoint. If the datapoint has no scaling, val is returned.
+// Strings are returned without NUL padding.
+func (dp Datapoint) ScaledValue(val any) any {
+	if s, ok := val.(string); ok {
+		return trimString(s)
+	}
+
+	if val == nil || dp.Scaling == nil || !dp.Scaling.Valid() {
+		return val
+	}
+
+	return dp.Scaling.apply(to.Float64(val))
+}
+
+// FormattedValue returns the scaled value including the unit.
+func (dp Datapoint) FormattedValue() string {
+	return dp.fmtValue(dp.Value)
+}
+
 func (dp Datapoint) fmtValue(val any) string {
 	if val == nil {
 		return "-"
 	}
-	if dp.Scaling == nil || !dp.Scaling.Valid() {
-		return fmt.Sprintf("%v%s", val, dp.Unit)
+	if s, ok := val.(string); ok {
+		return fmt.Sprintf("%s%s", trimString(s), dp.Unit)
 	}
 
-	minIn, maxIn, minOut, maxOut := dp.Scaling.Ranges()
-	inSize := math.Abs(minIn - maxIn)
-	outSize := math.Abs(minOut - maxOut)
-	floatVal := to.Float64(val)
-	res := (floatVal-minIn)/inSize*outSize + minOut
-	return fmt.Sprintf("%0.2f%s", res, dp.Unit)
+	str := fmt.Sprintf("%v%s", val, dp.Unit)
+	if dp.Scaling != nil && dp.Scaling.Valid() {
+		str = dp.Scaling.format(to.Float64(dp.ScaledValue(val))) + dp.Unit
+	}
+
+	if label := dp.label(val); label != "" {
+		str = fmt.Sprintf("%s (%s)", str, label)
+	}
+
+	return str
 }
 
 func (dp Datapoint) TableRow() table.Row {
 	value := dp.fmtValue(dp.Value)
-	if dp.Err != nil {
+	switch {
+	case dp.Err != nil:
 		value = Theme.Focused.ErrorMessage.Render(dp.Err.Error())
+	case dp.Written != nil:
+		value = Theme.Focused.ErrorMessage.Render(fmt.Sprintf("%s (wrote %s)", value, dp.fmtValue(dp.Written)))
+	}
+
+	if dp.Reading {
+		if dp.Value == nil && dp.Err == nil {
+			value = "reading…"
+		} else {
+			value += " …"
+		}
 	}
 
 	flags := "R"
@@ -97,8 +356,10 @@ func (dp Datapoint) TableRow() table.Row {
 	}
 
 	return table.Row{
+		connectionName(dp.Connection),
 		fmt.Sprintf("%d", dp.SlaveId),
 		fmt.Sprintf("%d", dp.Addr),
+		dp.Space.String(),
 		flags,
 		dp.Name,
 		dp.Description,
@@ -106,25 +367,9 @@ func (dp Datapoint) TableRow() table.Row {
 	}
 }
 
-type Scaling struct {
-	MinIn  string `json:"minIn"`
-	MaxIn  string `json:"maxIn"`
-	MinOut string `json:"minOut"`
-	MaxOut string `json:"maxOut"`
-}
-
-func (s Scaling) Valid() bool {
-	return len(s.MinIn) > 0 && len(s.MaxIn) > 0 && len(s.MinOut) > 0 && len(s.MaxOut) > 0
-}
-func (dp Scaling) Ranges() (minIn float64, maxIn float64, minOut float64, maxOut float64) {
-	minIn = to.Float64(dp.MinIn)
-	maxIn = to.Float64(dp.MaxIn)
-	minOut = to.Float64(dp.MinOut)
-	maxOut = to.Float64(dp.MaxOut)
-	return
-}
-
-// promptWrite prompts to write a value to a datapoint.
+// promptWrite prompts to write a value to a datapoint and returns
+// the raw value. Values of scaled datapoints are entered as scaled
+// values and converted to raw values.
 func promptWrite(dp Datapoint) (any, error) {
 	write := true
 	var val string = "1"
@@ -132,13 +377,64 @@ func promptWrite(dp Datapoint) (any, error) {
 		val = fmt.Sprintf("%v", dp.Value)
 	}
 
+	input := huh.NewInput().
+		Title(fmt.Sprintf(`Write value to "%s"`, dp.Name)).
+		Value(&val).
+		Validate(func(s string) error {
+			raw, err := dp.ParseScaledValue(s)
+			if err != nil {
+				return err
+			}
+			return dp.CheckLimits(raw)
+		})
+
+	switch {
+	case dp.DataType == DataTypeString:
+		val = ""
+		if s, ok := dp.Value.(string); ok {
+			val = trimString(s)
+		}
+	case dp.scalesOnWrite():
+		if dp.Value != nil {
+			val = dp.Scaling.format(to.Float64(dp.ScaledValue(dp.Value)))
+		}
+
+		input.DescriptionFunc(func() string {
+			raw, err := dp.ParseScaledValue(val)
+			if err != nil {
+				return ""
+			}
+			return fmt.Sprintf("Raw value %v", raw)
+		}, &val)
+	}
+
+	// Labels are selected instead of entering a raw value
+	var field huh.Field = input
+	parse := dp.ParseScaledValue
+	if len(dp.Labels) > 0 {
+		if v, ok := intValue(dp.Value); ok {
+			val = fmt.Sprintf("%d", v)
+		}
+
+		field = huh.NewSelect[string]().
+			Title(fmt.Sprintf(`Write value to "%s"`, dp.Name)).
+			Options(dp.labelOptions()...).
+			Validate(func(s string) error {
+				raw, err := dp.ParseValue(s)
+				if err != nil {
+					return err
+				}
+				return dp.CheckLimits(raw)
+			}).
+			Value(&val)
+		parse = dp.ParseValue
+	}
+
 	km := huh.NewDefaultKeyMap()
 	km.Quit.SetKeys("esc")
 	err := huh.NewForm(
 		huh.NewGroup(
-			huh.NewInput().
-				Title(fmt.Sprintf(`Write value to "%s"`, dp.Name)).
-				Value(&val),
+			field,
 
 			huh.NewConfirm().
 				Affirmative("Write").
@@ -149,15 +445,67 @@ func promptWrite(dp Datapoint) (any, error) {
 		WithKeyMap(km).
 		Run()
 
+	if err != nil {
+		return nil, err
+	}
+
 	if !write {
 		return nil, errors.New("canceled")
 	}
 
-	return val, err
+	raw, err := parse(val)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dp.CheckLimits(raw); err != nil {
+		return nil, err
+	}
+
+	if err := promptConfirmWrite(dp, raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// promptConfirmWrite lets the user confirm writing the raw value
+// a second time if the datapoint requires it.
+func promptConfirmWrite(dp Datapoint, raw any) error {
+	if !dp.Confirm {
+		return nil
+	}
+
+	km := huh.NewDefaultKeyMap()
+	km.Quit.SetKeys("esc")
+
+	write := false
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf(`Really write %s to "%s"?`, dp.fmtValue(raw), dp.Name)).
+				Description(fmt.Sprintf("Raw value %v", raw)).
+				Affirmative("Write").
+				Negative("Cancel").
+				Value(&write),
+		),
+	).
+		WithKeyMap(km).
+		Run()
+
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		return errors.New("canceled")
+	}
+
+	return nil
 }
 
 // promptDatapoint shows the editable fields of a datapoint.
-func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
+func promptDatapoint(dp Datapoint, title string, connections []string) (Datapoint, error) {
 	newScaling := func(minIn, maxIn, minOut, maxOut any) Scaling {
 		return Scaling{
 			MinIn:  fmt.Sprintf("%v", minIn),
@@ -176,40 +524,118 @@ func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
 		DataTypeFloat32: newScaling(0, max32, 0, max32),
 		DataTypeFloat64: newScaling(0, max32, 0, max32),
 		DataTypeUint64:  newScaling(0, max32, 0, max32),
+		DataTypeInt16:   newScaling(math.MinInt16, math.MaxInt16, math.MinInt16, math.MaxInt16),
+		DataTypeInt32:   newScaling(math.MinInt32, math.MaxInt32, math.MinInt32, math.MaxInt32),
+		DataTypeInt64:   newScaling(int64(math.MinInt64), int64(math.MaxInt64), int64(math.MinInt64), int64(math.MaxInt64)),
 	}
 
-	if scaling, ok := scalings[dp.DataType]; ok && dp.Scaling != nil {
-		scaling.MinIn = dp.Scaling.MinIn
-		scaling.MinOut = dp.Scaling.MinOut
-		scaling.MaxIn = dp.Scaling.MaxIn
-		scaling.MaxOut = dp.Scaling.MaxOut
-		scalings[dp.DataType] = scaling
-	}
-
+	// Linear scalings default to the range of the data type
 	scaling := scalings[dp.DataType]
+	scaling.Decimals = defaultDecimals
+	if dp.Scaling != nil {
+		// The expression is parsed while it's edited
+		scaling = *dp.Scaling
+		scaling.expr = nil
+	}
 
-	validateInt := (func(s string) error {
-		if len(s) == 0 {
-			return nil
+	// scales returns a function which hides a group
+	// unless one of the scaling kinds is selected.
+	scales := func(kinds ...ScalingKind) func() bool {
+		return func() bool {
+			for _, k := range kinds {
+				if scaling.Kind == k {
+					return false
+				}
+			}
+			return true
 		}
-		_, err := strconv.Atoi(s)
-		if err != nil {
-			return errors.New("input not a number")
+	}
+
+	gen := Generator{}
+	if dp.Generator != nil {
+		gen = *dp.Generator
+	}
+	// generates returns a function which hides a
+	// group unless one of the generator kinds is selected.
+	generates := func(kinds ...GeneratorKind) func() bool {
+		return func() bool {
+			for _, k := range kinds {
+				if gen.Kind == k {
+					return false
+				}
+			}
+			return true
 		}
-		return nil
-	})
+	}
 
 	theme := Theme
 	theme.Focused.Title = theme.Focused.Title.Width(15).AlignHorizontal(lipgloss.Right)
 	theme.Blurred.Title = theme.Blurred.Title.Foreground(theme.Blurred.TextInput.Text.GetForeground()).Width(15).AlignHorizontal(lipgloss.Right)
 
+	floatInput := func(title string, val *float64) huh.Field {
+		return huh.NewInput().
+			Title(title).
+			Prompt(":").
+			Validate(validateFloat).
+			Inline(true).
+			Accessor(NewFloatAccessor(val)).
+			WithTheme(theme)
+	}
+
+	textInput := func(title string, val *string, validate func(string) error) huh.Field {
+		return huh.NewInput().
+			Title(title).
+			Prompt(":").
+			Validate(validate).
+			Inline(true).
+			Value(val).
+			WithTheme(theme)
+	}
+
+	decimalsInput := func() huh.Field {
+		return newIntInput(0, 10).
+			Title("Decimals").
+			Prompt(":").
+			Inline(true).
+			Accessor(NewNumberAccessor(&scaling.Decimals)).
+			WithTheme(theme)
+	}
+
+	durationInput := func(title string, val *Duration, placeholder time.Duration) huh.Field {
+		return huh.NewInput().
+			Title(title).
+			Prompt(":").
+			Placeholder(placeholder.String()).
+			Validate(validateDuration).
+			Inline(true).
+			Accessor(NewDurationAccessor(val)).
+			WithTheme(theme)
+	}
+
 	km := huh.NewDefaultKeyMap()
 	km.Quit.SetKeys("esc")
 
+	// The connection can only be selected
+	// if there are named connections
+	var fields []huh.Field
+	if len(connections) > 0 {
+		options := []huh.Option[string]{huh.NewOption(defaultConnectionName, "")}
+		for _, name := range connections {
+			options = append(options, huh.NewOption(name, name))
+		}
+
+		fields = append(fields, huh.NewSelect[string]().
+			Title("Connection").
+			Inline(true).
+			Options(options...).
+			Value(&dp.Connection).
+			WithTheme(theme))
+	}
+
 	old := dp
 	save := true
 	err := huh.NewForm(
-		huh.NewGroup(
+		huh.NewGroup(append(fields,
 			huh.NewInput().
 				Title("Server ID").
 				Prompt(":").
@@ -240,6 +666,18 @@ func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
 				Accessor(NewNumberAccessor(&dp.Addr)).
 				WithTheme(theme),
 
+			huh.NewSelect[Space]().
+				Title("Space").
+				Inline(true).
+				Options(
+					huh.NewOption("Holding Register", SpaceHoldingRegister),
+					huh.NewOption("Input Register", SpaceInputRegister),
+					huh.NewOption("Coil", SpaceCoil),
+					huh.NewOption("Discrete Input", SpaceDiscreteInput),
+				).
+				Value(&dp.Space).
+				WithTheme(theme),
+
 			huh.NewSelect[Flag]().
 				Title("Flags").
 				Inline(true).
@@ -261,17 +699,33 @@ func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
 				Title("Datatype").
 				Inline(true).
 				Options(
-					huh.NewOption("Coil", DataTypeCoil),
 					huh.NewOption("Bool", DataTypeBool),
 					huh.NewOption("Uint16", DataTypeUint16),
 					huh.NewOption("Uint32", DataTypeUint32),
 					huh.NewOption("Uint64", DataTypeUint64),
+					huh.NewOption("Int16", DataTypeInt16),
+					huh.NewOption("Int32", DataTypeInt32),
+					huh.NewOption("Int64", DataTypeInt64),
 					huh.NewOption("Float32", DataTypeFloat32),
 					huh.NewOption("Float64", DataTypeFloat64),
+					huh.NewOption("String", DataTypeString),
+					huh.NewOption("Bits", DataTypeBits),
 				).
 				Value(&dp.DataType).
 				WithTheme(theme),
 
+			huh.NewSelect[ByteOrder]().
+				Title("Byte Order").
+				Inline(true).
+				Options(
+					huh.NewOption("ABCD (big endian)", ByteOrderABCD),
+					huh.NewOption("DCBA (little endian)", ByteOrderDCBA),
+					huh.NewOption("BADC (byte swap)", ByteOrderBADC),
+					huh.NewOption("CDAB (word swap)", ByteOrderCDAB),
+				).
+				Value(&dp.ByteOrder).
+				WithTheme(theme),
+
 			huh.NewInput().
 				Title("Unit").
 				Prompt(":").
@@ -280,45 +734,160 @@ func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
 				WithTheme(theme),
 
 			huh.NewInput().
-				Title("Input Min").
+				Title("Labels").
 				Prompt(":").
-				Validate(validateInt).
+				Placeholder("e.g. 0=Off, 1=On, 3=Boost").
+				Validate(validateLabels[int64]()).
 				Inline(true).
-				Value(&scaling.MinIn).
+				Accessor(NewLabelsAccessor(&dp.Labels)).
 				WithTheme(theme),
 
 			huh.NewInput().
-				Title("Max").
+				Title("Bit Labels").
+				Prompt(":").
+				Placeholder("e.g. 0=Alarm, 4=Filter").
+				Validate(validateLabels[uint8]()).
+				Inline(true).
+				Accessor(NewLabelsAccessor(&dp.BitLabels)).
+				WithTheme(theme),
+
+			huh.NewInput().
+				Title("Poll Interval").
+				Prompt(":").
+				Placeholder("default").
+				Validate(validateDuration).
+				Inline(true).
+				Accessor(NewDurationAccessor(&dp.PollInterval)).
+				WithTheme(theme),
+
+			huh.NewSelect[ScalingKind]().
+				Title("Scaling").
+				Inline(true).
+				Options(
+					huh.NewOption("None", ScalingNone),
+					huh.NewOption("Linear Range", ScalingLinear),
+					huh.NewOption("Factor & Offset", ScalingFactor),
+					huh.NewOption("Expression", ScalingExpression),
+				).
+				Value(&scaling.Kind).
+				WithTheme(theme),
+
+			huh.NewSelect[GeneratorKind]().
+				Title("Simulation").
+				Inline(true).
+				Options(
+					huh.NewOption("None", GeneratorNone),
+					huh.NewOption("Constant", GeneratorConstant),
+					huh.NewOption("Ramp", GeneratorRamp),
+					huh.NewOption("Sine", GeneratorSine),
+					huh.NewOption("Random Walk", GeneratorRandomWalk),
+					huh.NewOption("Square Wave", GeneratorSquare),
+					huh.NewOption("Replay CSV", GeneratorReplay),
+				).
+				Value(&gen.Kind).
+				WithTheme(theme),
+		)...).
+			Title(title), // TODO: Doesn't seem to do anything; see https://github.com/charmbracelet/huh/issues/298
+
+		huh.NewGroup(
+			textInput("Input Min", &scaling.MinIn, validateFloat),
+			textInput("Max", &scaling.MaxIn, validateFloat),
+			textInput("Output Min", &scaling.MinOut, validateFloat),
+			textInput("Max", &scaling.MaxOut, validateFloat),
+			decimalsInput(),
+		).WithHideFunc(scales(ScalingLinear)),
+
+		huh.NewGroup(
+			textInput("Factor", &scaling.Factor, validateFloat),
+			textInput("Offset", &scaling.Offset, validateFloat),
+			decimalsInput(),
+		).WithHideFunc(scales(ScalingFactor)),
+
+		huh.NewGroup(
+			textInput("Expression", &scaling.Expression, validateExpression),
+			decimalsInput(),
+		).WithHideFunc(scales(ScalingExpression)),
+
+		huh.NewGroup(
+			newIntInput(1, maxRegistersPerRead).
+				Title("Length").
 				Prompt(":").
-				Validate(validateInt).
+				Description("Number of registers (2 characters each)").
 				Inline(true).
-				Value(&scaling.MaxIn).
+				Accessor(NewNumberAccessor(&dp.Length)).
 				WithTheme(theme),
+		).WithHideFunc(func() bool {
+			return dp.DataType != DataTypeString
+		}),
 
+		huh.NewGroup(
 			huh.NewInput().
-				Title("Output Min").
+				Title("Bits").
 				Prompt(":").
-				Validate(validateInt).
+				Description("Bit (e.g. 3), range of bits (e.g. 4-7) or mask (e.g. 0x00F0)").
+				Validate(validateBitMask).
 				Inline(true).
-				Value(&scaling.MinOut).
+				Accessor(NewBitMaskAccessor(&dp.Mask)).
 				WithTheme(theme),
+		).WithHideFunc(func() bool {
+			return dp.DataType != DataTypeBits
+		}),
+
+		huh.NewGroup(
+			textInput("Write Min", &dp.MinWrite, validateFloat),
+			textInput("Max", &dp.MaxWrite, validateFloat),
+			huh.NewConfirm().
+				Title("Confirm").
+				Description("Confirm writes a second time").
+				Inline(true).
+				Value(&dp.Confirm).
+				WithTheme(theme),
+			durationInput("Pulse", &dp.PulseDuration, defaultPulseDuration),
+		).WithHideFunc(func() bool {
+			return !dp.Space.IsWritable()
+		}),
+
+		huh.NewGroup(
+			floatInput("Value", &gen.Value),
+			durationInput("Interval", &gen.Interval, defaultGeneratorInterval),
+		).WithHideFunc(generates(GeneratorConstant)),
 
+		huh.NewGroup(
+			floatInput("Min", &gen.Min),
+			floatInput("Max", &gen.Max),
+			durationInput("Period", &gen.Period, defaultGeneratorPeriod),
+			durationInput("Interval", &gen.Interval, defaultGeneratorInterval),
+		).WithHideFunc(generates(GeneratorRamp, GeneratorSine, GeneratorSquare)),
+
+		huh.NewGroup(
+			floatInput("Min", &gen.Min),
+			floatInput("Max", &gen.Max),
+			floatInput("Step", &gen.Step),
+			durationInput("Interval", &gen.Interval, defaultGeneratorInterval),
+		).WithHideFunc(generates(GeneratorRandomWalk)),
+
+		huh.NewGroup(
 			huh.NewInput().
-				Title("Max").
+				Title("CSV File").
 				Prompt(":").
-				Validate(validateInt).
+				Validate(func(s string) error {
+					_, err := loadReplay(s)
+					return err
+				}).
 				Inline(true).
-				Value(&scaling.MaxOut).
+				Value(&gen.File).
 				WithTheme(theme),
+			durationInput("Interval", &gen.Interval, defaultGeneratorInterval),
+		).WithHideFunc(generates(GeneratorReplay)),
 
+		huh.NewGroup(
 			huh.NewConfirm().
 				Key("s").
 				Affirmative("Save").
 				Negative("Cancel").
 				Value(&save).
 				WithTheme(theme),
-		).
-			Title(title), // TODO: Doesn't seem to do anything; see https://github.com/charmbracelet/huh/issues/298
+		),
 	).
 		WithKeyMap(km).
 		Run()
@@ -326,7 +895,16 @@ func promptDatapoint(dp Datapoint, title string) (Datapoint, error) {
 	if !save || err != nil {
 		return old, errors.New("canceled")
 	}
-	dp.Scaling = &scaling
+	dp.Scaling = nil
+	if scaling.Kind != ScalingNone {
+		scaling.compile()
+		dp.Scaling = &scaling
+	}
+
+	dp.Generator = nil
+	if gen.Kind != GeneratorNone {
+		dp.Generator = &gen
+	}
 
 	return dp, nil
 }
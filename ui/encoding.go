@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xiam/to"
+)
+
+// RegisterCount returns the number of 16-bit registers
+// which are needed to store a value of the data type.
+func (dt DataType) RegisterCount() uint16 {
+	switch dt {
+	case DataTypeUint32, DataTypeInt32, DataTypeFloat32:
+		return 2
+	case DataTypeUint64, DataTypeInt64, DataTypeFloat64:
+		return 4
+	}
+
+	return 1
+}
+
+// codec specifies how the value of a datapoint is stored. The
+// poller and the simulator encode and decode values with the codec
+// of a datapoint instead of a copy of the datapoint, whose value is
+// changed by the user interface meanwhile.
+type codec struct {
+	Space     Space
+	DataType  DataType
+	ByteOrder ByteOrder
+	Length    uint16
+	Mask      BitMask
+}
+
+// codec returns the codec of the datapoint.
+func (dp *Datapoint) codec() codec {
+	return codec{
+		Space:     dp.Space,
+		DataType:  dp.DataType,
+		ByteOrder: dp.ByteOrder,
+		Length:    dp.Length,
+		Mask:      dp.Mask,
+	}
+}
+
+// registerCount returns the number of 16-bit
+// registers which store the value of the datapoint.
+func (c codec) registerCount() uint16 {
+	if c.DataType == DataTypeString {
+		return max(c.Length, 1)
+	}
+	return c.DataType.RegisterCount()
+}
+
+// trimString removes the NUL padding of a string.
+func trimString(s string) string {
+	return strings.TrimRight(s, "\x00")
+}
+
+// ParseValue parses a value of the datapoint from a string.
+func (dp Datapoint) ParseValue(s string) (any, error) {
+	var val any
+	var err error
+	switch {
+	case dp.Space.IsBit(), dp.DataType == DataTypeBool, dp.DataType == DataTypeCoil:
+		val, err = strconv.ParseBool(s)
+	case dp.DataType == DataTypeUint16:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, 16)
+		val = uint16(u)
+	case dp.DataType == DataTypeUint32:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, 32)
+		val = uint32(u)
+	case dp.DataType == DataTypeUint64:
+		val, err = strconv.ParseUint(s, 0, 64)
+	case dp.DataType == DataTypeInt16:
+		var i int64
+		i, err = strconv.ParseInt(s, 0, 16)
+		val = int16(i)
+	case dp.DataType == DataTypeInt32:
+		var i int64
+		i, err = strconv.ParseInt(s, 0, 32)
+		val = int32(i)
+	case dp.DataType == DataTypeInt64:
+		val, err = strconv.ParseInt(s, 0, 64)
+	case dp.DataType == DataTypeFloat32:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		val = float32(f)
+	case dp.DataType == DataTypeFloat64:
+		val, err = strconv.ParseFloat(s, 64)
+	case dp.DataType == DataTypeBits && dp.Mask.single():
+		val, err = strconv.ParseBool(s)
+	case dp.DataType == DataTypeBits:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, 16)
+		if err == nil && u&^uint64(dp.Mask.max()) != 0 {
+			return nil, fmt.Errorf("value %d doesn't fit into bits %s", u, dp.Mask)
+		}
+		val = uint16(u)
+	case dp.DataType == DataTypeString:
+		if n := int(dp.codec().registerCount()) * 2; len(s) > n {
+			return nil, fmt.Errorf("string longer than %d characters", n)
+		}
+		val = s
+	default:
+		return nil, fmt.Errorf("unsupported data type %s", dp.DataType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(`invalid %s value "%s"`, dp.DataType, s)
+	}
+
+	return val, nil
+}
+
+// reorder converts registers from the byte order into
+// big endian with the high word first, and vice versa.
+func (o ByteOrder) reorder(regs []uint16) []uint16 {
+	res := slices.Clone(regs)
+	switch o {
+	case ByteOrderDCBA, ByteOrderCDAB:
+		slices.Reverse(res)
+	}
+
+	switch o {
+	case ByteOrderDCBA, ByteOrderBADC:
+		for i, r := range res {
+			res[i] = r<<8 | r>>8
+		}
+	}
+
+	return res
+}
+
+// decodeRegisters returns the value of the datapoint
+// stored in the registers.
+func (c codec) decodeRegisters(regs []uint16) any {
+	regs = c.ByteOrder.reorder(regs)
+	buf := make([]byte, len(regs)*2)
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(buf[i*2:], r)
+	}
+
+	switch c.DataType {
+	case DataTypeBool, DataTypeCoil:
+		return regs[0] != 0
+	case DataTypeUint16:
+		return regs[0]
+	case DataTypeInt16:
+		return int16(regs[0])
+	case DataTypeUint32:
+		return binary.BigEndian.Uint32(buf)
+	case DataTypeInt32:
+		return int32(binary.BigEndian.Uint32(buf))
+	case DataTypeUint64:
+		return binary.BigEndian.Uint64(buf)
+	case DataTypeInt64:
+		return int64(binary.BigEndian.Uint64(buf))
+	case DataTypeFloat32:
+		return math.Float32frombits(binary.BigEndian.Uint32(buf))
+	case DataTypeFloat64:
+		return math.Float64frombits(binary.BigEndian.Uint64(buf))
+	case DataTypeString:
+		return string(buf)
+	case DataTypeBits:
+		return c.decodeBits(regs[0])
+	}
+
+	return nil
+}
+
+// encodeValue returns the registers which store
+// the value of the datapoint.
+func (c codec) encodeValue(val any) []uint16 {
+	buf := make([]byte, c.registerCount()*2)
+	switch c.DataType {
+	case DataTypeBool, DataTypeCoil:
+		if to.Bool(val) {
+			buf[1] = 1
+		}
+	case DataTypeUint16:
+		binary.BigEndian.PutUint16(buf, uint16(to.Uint64(val)))
+	case DataTypeInt16:
+		binary.BigEndian.PutUint16(buf, uint16(int16(to.Int64(val))))
+	case DataTypeUint32:
+		binary.BigEndian.PutUint32(buf, uint32(to.Uint64(val)))
+	case DataTypeInt32:
+		binary.BigEndian.PutUint32(buf, uint32(int32(to.Int64(val))))
+	case DataTypeUint64:
+		binary.BigEndian.PutUint64(buf, to.Uint64(val))
+	case DataTypeInt64:
+		binary.BigEndian.PutUint64(buf, uint64(to.Int64(val)))
+	case DataTypeFloat32:
+		binary.BigEndian.PutUint32(buf, math.Float32bits(float32(to.Float64(val))))
+	case DataTypeFloat64:
+		binary.BigEndian.PutUint64(buf, math.Float64bits(to.Float64(val)))
+	case DataTypeBits:
+		binary.BigEndian.PutUint16(buf, c.encodeBits(val))
+	case DataTypeString:
+		// Strings are padded with NUL and truncated to the length
+		copy(buf, fmt.Sprint(val))
+	}
+
+	regs := make([]uint16, len(buf)/2)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(buf[i*2:])
+	}
+
+	return c.ByteOrder.reorder(regs)
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is an arithmetic expression over the raw value
+// of a datapoint, e.g. "raw * 0.1 - 40".
+type expression func(raw float64) float64
+
+// parseExpression parses an expression of numbers (e.g. 0.1 or 1e-3),
+// the variable raw, the operators +, -, * and / and parentheses.
+func parseExpression(s string) (expression, error) {
+	p := &expressionParser{s: s}
+	expr, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, fmt.Errorf(`unexpected "%s"`, p.s[p.pos:])
+	}
+
+	return expr, nil
+}
+
+// expressionParser is a recursive descent parser of expressions.
+type expressionParser struct {
+	s   string
+	pos int
+}
+
+func (p *expressionParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// consume returns true and advances if the next character is c.
+func (p *expressionParser) consume(c byte) bool {
+	if p.skipSpace(); p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseSum parses terms separated by + and -.
+func (p *expressionParser) parseSum() (expression, error) {
+	return p.parseBinary(p.parseProduct, "+-")
+}
+
+// parseProduct parses factors separated by * and /.
+func (p *expressionParser) parseProduct() (expression, error) {
+	return p.parseBinary(p.parseUnary, "*/")
+}
+
+// parseBinary parses operands separated by the operators,
+// which are evaluated from left to right.
+func (p *expressionParser) parseBinary(operand func() (expression, error), operators string) (expression, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) || !strings.ContainsRune(operators, rune(p.s[p.pos])) {
+			return left, nil
+		}
+		op := p.s[p.pos]
+		p.pos++
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = applyOperator(op, left, right)
+	}
+}
+
+// applyOperator returns an expression which applies
+// the operator to the left and right expression.
+func applyOperator(op byte, left, right expression) expression {
+	switch op {
+	case '+':
+		return func(raw float64) float64 { return left(raw) + right(raw) }
+	case '-':
+		return func(raw float64) float64 { return left(raw) - right(raw) }
+	case '*':
+		return func(raw float64) float64 { return left(raw) * right(raw) }
+	}
+
+	return func(raw float64) float64 { return left(raw) / right(raw) }
+}
+
+// parseUnary parses a factor with an optional sign.
+func (p *expressionParser) parseUnary() (expression, error) {
+	switch {
+	case p.consume('-'):
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(raw float64) float64 { return -expr(raw) }, nil
+	case p.consume('+'):
+		return p.parseUnary()
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a number, the variable raw
+// or an expression in parentheses.
+func (p *expressionParser) parsePrimary() (expression, error) {
+	if p.consume('(') {
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.consume(')') {
+			return nil, errors.New(`missing ")"`)
+		}
+		return expr, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && (p.isTokenChar(p.s[p.pos]) || p.isExponentSign(start)) {
+		p.pos++
+	}
+	token := p.s[start:p.pos]
+
+	switch {
+	case token == "":
+		if p.pos == len(p.s) {
+			return nil, errors.New("unexpected end of expression")
+		}
+		return nil, fmt.Errorf(`unexpected "%s"`, p.s[p.pos:])
+	case strings.EqualFold(token, "raw"):
+		return func(raw float64) float64 { return raw }, nil
+	}
+
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid number "%s"`, token)
+	}
+
+	return func(float64) float64 { return f }, nil
+}
+
+func (p *expressionParser) isTokenChar(c byte) bool {
+	return unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || c == '.'
+}
+
+// isExponentSign returns true if the current character is the sign
+// of the exponent of a number token which starts at start, e.g. 1e-3.
+func (p *expressionParser) isExponentSign(start int) bool {
+	if p.pos <= start+1 || p.s[p.pos] != '-' && p.s[p.pos] != '+' {
+		return false
+	}
+
+	first, prev := p.s[start], p.s[p.pos-1]
+	return (unicode.IsDigit(rune(first)) || first == '.') && (prev == 'e' || prev == 'E')
+}
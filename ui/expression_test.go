@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		s   string
+		raw float64
+		val float64
+	}{
+		{"raw * 0.1 - 40", 500, 10},
+		{"-(raw + 2) / 4", 6, -2},
+		{"raw * 1e-3", 1500, 1.5},
+		{"raw * 1E+2", 3, 300},
+		{"2.5e2 - raw", 50, 200},
+		{".5e1*raw", 2, 10},
+		{"raw-1e1", 20, 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			expr, err := parseExpression(test.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := expr(test.raw); v != test.val {
+				t.Fatalf("%v != %v", v, test.val)
+			}
+		})
+	}
+}
+
+func TestParseInvalidExpression(t *testing.T) {
+	for _, s := range []string{"", "raw *", "1e-", "1e", "(raw", "raw e-3", "rawe-3", "x"} {
+		if _, err := parseExpression(s); err == nil {
+			t.Fatalf(`"%s" parsed`, s)
+		}
+	}
+}
+
+func TestScalingCompilesExpression(t *testing.T) {
+	var s Scaling
+	if err := json.Unmarshal([]byte(`{"kind":3,"expression":"raw * 1e-1","decimals":1}`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.expr == nil {
+		t.Fatal("expression not compiled")
+	}
+
+	if v := s.apply(25); v != 2.5 {
+		t.Fatalf("%v != 2.5", v)
+	}
+}
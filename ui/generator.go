@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultGeneratorInterval is the duration between
+	// generated values if a generator doesn't specify it.
+	defaultGeneratorInterval = time.Second
+
+	// defaultGeneratorPeriod is the period of ramp,
+	// sine and square wave generators.
+	defaultGeneratorPeriod = time.Minute
+)
+
+// GeneratorKind represents the kind of values a generator produces.
+type GeneratorKind byte
+
+const (
+	GeneratorNone GeneratorKind = iota
+	GeneratorConstant
+	GeneratorRamp
+	GeneratorSine
+	GeneratorRandomWalk
+	GeneratorSquare
+	GeneratorReplay
+)
+
+func (k GeneratorKind) String() string {
+	switch k {
+	case GeneratorNone:
+		return "none"
+	case GeneratorConstant:
+		return "constant"
+	case GeneratorRamp:
+		return "ramp"
+	case GeneratorSine:
+		return "sine"
+	case GeneratorRandomWalk:
+		return "random walk"
+	case GeneratorSquare:
+		return "square wave"
+	case GeneratorReplay:
+		return "replay"
+	}
+	return "?"
+}
+
+// Generator produces simulated values of a datapoint when
+// serving datapoints. The values are raw register values,
+// i.e. they are stored without scaling.
+type Generator struct {
+	Kind GeneratorKind `json:"kind"`
+
+	// Interval is the duration between generated values.
+	Interval Duration `json:"interval,omitempty"`
+
+	// Value is the value of a constant generator.
+	Value float64 `json:"value,omitempty"`
+
+	// Min and Max are the range of ramp, sine,
+	// square wave and random walk generators.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+
+	// Period is the duration of a cycle of ramp,
+	// sine and square wave generators.
+	Period Duration `json:"period,omitempty"`
+
+	// Step is the maximum change of a random walk per value.
+	Step float64 `json:"step,omitempty"`
+
+	// File is the path of the csv file whose rows are replayed.
+	// The value of a row is stored in the last column.
+	File string `json:"file,omitempty"`
+}
+
+// generatorState is the state of a running generator.
+type generatorState struct {
+	start time.Time
+	next  time.Time
+
+	// value is the current value of a random walk.
+	value float64
+
+	// rows are the values which are replayed.
+	rows  []float64
+	index int
+}
+
+func (g Generator) interval() time.Duration {
+	if g.Interval > 0 {
+		return time.Duration(g.Interval)
+	}
+	return defaultGeneratorInterval
+}
+
+func (g Generator) period() time.Duration {
+	if g.Period > 0 {
+		return time.Duration(g.Period)
+	}
+	return defaultGeneratorPeriod
+}
+
+// start returns the state of the generator starting at now.
+func (g Generator) start(now time.Time) (*generatorState, error) {
+	st := &generatorState{
+		start: now,
+		next:  now,
+		value: g.Min + (g.Max-g.Min)/2,
+	}
+
+	if g.Kind == GeneratorReplay {
+		rows, err := loadReplay(g.File)
+		if err != nil {
+			return nil, err
+		}
+		st.rows = rows
+	}
+
+	return st, nil
+}
+
+// next returns the next value of the generator.
+func (g Generator) next(st *generatorState, now time.Time) float64 {
+	elapsed := now.Sub(st.start) % g.period()
+	phase := float64(elapsed) / float64(g.period())
+
+	switch g.Kind {
+	case GeneratorRamp:
+		return g.Min + (g.Max-g.Min)*phase
+	case GeneratorSine:
+		return g.Min + (g.Max-g.Min)/2*(1+math.Sin(2*math.Pi*phase))
+	case GeneratorSquare:
+		if phase < 0.5 {
+			return g.Min
+		}
+		return g.Max
+	case GeneratorRandomWalk:
+		st.value += (rand.Float64()*2 - 1) * g.Step
+		st.value = max(g.Min, min(g.Max, st.value))
+		return st.value
+	case GeneratorReplay:
+		v := st.rows[st.index%len(st.rows)]
+		st.index++
+		return v
+	}
+
+	return g.Value
+}
+
+// generatedValue converts a generated value
+// into a value of the datapoint.
+func (c codec) generatedValue(v float64) any {
+	switch {
+	case c.Space.IsBit(), c.DataType == DataTypeBool, c.DataType == DataTypeCoil:
+		return v != 0
+	case c.DataType == DataTypeBits && c.Mask.single():
+		return v != 0
+	case c.DataType == DataTypeFloat32, c.DataType == DataTypeFloat64:
+		return v
+	}
+
+	return math.Round(v)
+}
+
+// loadReplay returns the values of the csv file at path.
+// A header row is skipped.
+func loadReplay(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	var rows []float64
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s := strings.TrimSpace(record[len(record)-1])
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf(`%s:%d: invalid value "%s"`, path, line, s)
+		}
+		rows = append(rows, v)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no values", path)
+	}
+
+	return rows, nil
+}
+
+// validateFloat validates an optional floating point number.
+func validateFloat(s string) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return errors.New("input not a number")
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// Results of written values
+const (
+	WriteOK       = "ok"
+	WriteMismatch = "mismatch"
+)
+
+// HistoryEntry is a value which was written to a datapoint.
+// Values are raw values.
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection,omitempty"`
+	SlaveId    uint8     `json:"slaveId"`
+	Addr       uint16    `json:"addr"`
+	Space      Space     `json:"space"`
+	Name       string    `json:"name"`
+	Old        string    `json:"old"`
+	New        string    `json:"new"`
+
+	// ReadBack is the value which was read back after writing.
+	ReadBack string `json:"read-back,omitempty"`
+
+	// Result is either WriteOK, WriteMismatch
+	// or the error of the write.
+	Result string `json:"result"`
+}
+
+// NewHistoryEntry returns the entry of a write
+// of the value to the datapoint.
+func NewHistoryEntry(dp *Datapoint, old any, val any, readBack any, err error) HistoryEntry {
+	e := HistoryEntry{
+		Time:       time.Now(),
+		Connection: dp.Connection,
+		SlaveId:    dp.SlaveId,
+		Addr:       dp.Addr,
+		Space:      dp.Space,
+		Name:       dp.Name,
+		Old:        historyValue(old),
+		New:        historyValue(val),
+		ReadBack:   historyValue(readBack),
+		Result:     WriteOK,
+	}
+
+	switch {
+	case err != nil:
+		e.Result = err.Error()
+	case readBack != nil && !dp.codec().equalValues(readBack, val):
+		e.Result = WriteMismatch
+	}
+
+	return e
+}
+
+func historyValue(val any) string {
+	if val == nil {
+		return ""
+	}
+	if s, ok := val.(string); ok {
+		return trimString(s)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// History is an append-only log of written values,
+// which is stored as one json object per line.
+type History struct {
+	path string
+}
+
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Append appends the entry to the log.
+func (h *History) Append(e HistoryEntry) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Entries returns the entries of the log, the most recent first.
+// Lines which are no valid entries are skipped.
+func (h *History) Entries() ([]HistoryEntry, error) {
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	slices.Reverse(entries)
+
+	return entries, scanner.Err()
+}
+
+// historyView shows the written values.
+type historyView struct {
+	*table.Model
+
+	KeyMap HistoryKeyMap
+	Help   help.Model
+	Status *Status
+}
+
+func newHistoryView(theme *huh.Theme, entries []HistoryEntry) *historyView {
+	t := newStyledTable()
+	t.SetColumns([]table.Column{
+		{Title: "Time", Width: 19},
+		{Title: "Connection", Width: 10},
+		{Title: "Server ID", Width: 9},
+		{Title: "Address", Width: 7},
+		{Title: "Space", Width: 5},
+		{Title: "Name", Width: 20},
+		{Title: "Old", Width: 12},
+		{Title: "New", Width: 12},
+		{Title: "Read Back", Width: 12},
+		{Title: "Result", Width: 30},
+	})
+
+	rows := make([]table.Row, len(entries))
+	for i, e := range entries {
+		result := e.Result
+		if result != WriteOK {
+			result = theme.Focused.ErrorMessage.Render(result)
+		}
+
+		rows[i] = table.Row{
+			e.Time.Local().Format(time.DateTime),
+			connectionName(e.Connection),
+			fmt.Sprintf("%d", e.SlaveId),
+			fmt.Sprintf("%d", e.Addr),
+			e.Space.String(),
+			e.Name,
+			e.Old,
+			e.New,
+			e.ReadBack,
+			result,
+		}
+	}
+	t.SetRows(rows)
+	t.Focus()
+
+	v := &historyView{
+		Model:  &t,
+		KeyMap: DefaultHistoryKeyMap(t.KeyMap),
+		Help:   help.New(),
+		Status: NewStatus(theme),
+	}
+	v.Help.ShowAll = true
+	v.Status.Text = fmt.Sprintf("%d written values", len(entries))
+
+	return v
+}
+
+// update handles key messages. It returns false
+// if the user wants to leave the history.
+func (v *historyView) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if key.Matches(msg, v.KeyMap.Quit) {
+		return false, tea.ClearScreen
+	}
+
+	table, cmd := v.Model.Update(msg)
+	v.Model = &table
+
+	return true, cmd
+}
+
+func (v *historyView) View() string {
+	return baseStyle.Render(v.Model.View()) + "\n" + v.Status.View(v.Model.Width()) + "\n" + v.Help.View(v.KeyMap) + "\n"
+}
+
+type HistoryKeyMap struct {
+	Table table.KeyMap
+	Quit  key.Binding
+}
+
+func DefaultHistoryKeyMap(km table.KeyMap) HistoryKeyMap {
+	return HistoryKeyMap{
+		Table: km,
+		Quit: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc", "back"),
+		),
+	}
+}
+
+// ShortHelp implements the KeyMap interface.
+func (km HistoryKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{km.Table.LineUp, km.Table.LineDown, km.Quit}
+}
+
+// FullHelp implements the KeyMap interface.
+func (km HistoryKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{km.Table.LineUp, km.Table.LineDown}, {km.Quit}}
+}
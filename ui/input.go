@@ -28,10 +28,44 @@ func (a *NumberAccessor[T]) Get() string {
 	return fmt.Sprintf("%v", *a.val)
 }
 
+// Set sets the value to the integer of the string. Strings which
+// are not integers, or are out of the range of T, are ignored and
+// must be rejected by the validation of the input.
 func (a *NumberAccessor[T]) Set(value string) {
-	i, err := strconv.Atoi(value)
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if v := T(i); int64(v) == i && (v < 0) == (i < 0) {
+			*a.val = v
+		}
+		return
+	}
+
+	if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+		if v := T(u); uint64(v) == u && v >= 0 {
+			*a.val = v
+		}
+	}
+}
+
+// FloatAccessor accesses a float value, e.g. of scaled inputs.
+type FloatAccessor struct {
+	val *float64
+}
+
+func NewFloatAccessor(val *float64) *FloatAccessor {
+	return &FloatAccessor{val}
+}
+
+func (a *FloatAccessor) Get() string {
+	if a.val == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*a.val, 'f', -1, 64)
+}
+
+func (a *FloatAccessor) Set(value string) {
+	f, err := strconv.ParseFloat(value, 64)
 	if err == nil {
-		*a.val = T(i)
+		*a.val = f
 	}
 }
 
@@ -0,0 +1,41 @@
+package ui
+
+import "testing"
+
+func TestNumberAccessor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint8
+	}{
+		{"7", 7},
+		{"255", 255},
+		{"256", 1},
+		{"-1", 1},
+		{"1.5", 1},
+		{"abc", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			var v uint8 = 1
+			NewNumberAccessor(&v).Set(test.value)
+			if v != test.want {
+				t.Fatalf("%d != %d", v, test.want)
+			}
+		})
+	}
+}
+
+func TestFloatAccessor(t *testing.T) {
+	v := 1.0
+	a := NewFloatAccessor(&v)
+	a.Set("2.5")
+	if v != 2.5 || a.Get() != "2.5" {
+		t.Fatalf("%v, %s", v, a.Get())
+	}
+
+	a.Set("abc")
+	if v != 2.5 {
+		t.Fatalf("%v != 2.5", v)
+	}
+}
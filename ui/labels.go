@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/xiam/to"
+)
+
+// labelKey is the type of the values which are mapped to labels.
+type labelKey interface {
+	int64 | uint8
+}
+
+// parseLabels returns the labels specified as comma-separated
+// list of value=label pairs (e.g. "0=Off, 1=On, 3=Boost").
+func parseLabels[K labelKey](s string) (map[K]string, error) {
+	labels := map[K]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if len(strings.TrimSpace(pair)) == 0 {
+			continue
+		}
+
+		key, label, found := strings.Cut(pair, "=")
+		label = strings.TrimSpace(label)
+		if !found || len(label) == 0 {
+			return nil, fmt.Errorf(`invalid label "%s"`, strings.TrimSpace(pair))
+		}
+
+		k, err := strconv.ParseInt(strings.TrimSpace(key), 0, 64)
+		if err != nil || int64(K(k)) != k {
+			return nil, fmt.Errorf(`invalid value "%s"`, strings.TrimSpace(key))
+		}
+		labels[K(k)] = label
+	}
+
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	return labels, nil
+}
+
+// sortedKeys returns the values of the labels in ascending order.
+func sortedKeys[K labelKey](labels map[K]string) []K {
+	keys := make([]K, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// formatLabels returns the labels as comma-separated
+// list of value=label pairs, ordered by value.
+func formatLabels[K labelKey](labels map[K]string) string {
+	var pairs []string
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf("%d=%s", k, labels[k]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// LabelsAccessor accesses labels as a string.
+type LabelsAccessor[K labelKey] struct {
+	val *map[K]string
+}
+
+func NewLabelsAccessor[K labelKey](val *map[K]string) *LabelsAccessor[K] {
+	return &LabelsAccessor[K]{val}
+}
+
+func (a *LabelsAccessor[K]) Get() string {
+	return formatLabels(*a.val)
+}
+
+func (a *LabelsAccessor[K]) Set(value string) {
+	labels, err := parseLabels[K](value)
+	if err == nil {
+		*a.val = labels
+	}
+}
+
+// validateLabels returns a function which validates labels.
+func validateLabels[K labelKey]() func(string) error {
+	return func(s string) error {
+		_, err := parseLabels[K](s)
+		return err
+	}
+}
+
+// intValue returns the raw value as integer. False is
+// returned if the value is not an integer, e.g. a string.
+func intValue(val any) (int64, bool) {
+	switch v := val.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case float32:
+		return int64(v), float64(v) == math.Trunc(float64(v))
+	case float64:
+		return int64(v), v == math.Trunc(v)
+	case uint8, uint16, uint32, uint64, int8, int16, int32, int64, int:
+		return to.Int64(v), true
+	}
+
+	return 0, false
+}
+
+// label returns the label of the raw value, and the labels of
+// the set bits separated by comma. If there are no labels for
+// the value, an empty string is returned.
+func (dp Datapoint) label(val any) string {
+	v, ok := intValue(val)
+	if !ok {
+		return ""
+	}
+
+	if label, ok := dp.Labels[v]; ok {
+		return label
+	}
+
+	var labels []string
+	for _, bit := range sortedKeys(dp.BitLabels) {
+		if bit < 64 && v&(1<<bit) != 0 {
+			labels = append(labels, dp.BitLabels[bit])
+		}
+	}
+
+	return strings.Join(labels, ", ")
+}
+
+// LabelValue returns the raw value of the label,
+// or false if there is no such label.
+func (dp Datapoint) LabelValue(label string) (string, bool) {
+	for v, l := range dp.Labels {
+		if strings.EqualFold(l, label) {
+			return fmt.Sprintf("%d", v), true
+		}
+	}
+	return "", false
+}
+
+// labelOptions returns the labels as options of a select,
+// whose values are the raw values.
+func (dp Datapoint) labelOptions() []huh.Option[string] {
+	var options []huh.Option[string]
+	for _, v := range sortedKeys(dp.Labels) {
+		options = append(options, huh.NewOption(fmt.Sprintf("%d (%s)", v, dp.Labels[v]), fmt.Sprintf("%d", v)))
+	}
+	return options
+}
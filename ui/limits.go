@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/xiam/to"
+)
+
+// CheckLimits returns an error if the raw value is outside of
+// the write limits of the datapoint, which are scaled values.
+// Strings and bools are not limited.
+func (dp Datapoint) CheckLimits(val any) error {
+	switch val.(type) {
+	case string, bool:
+		return nil
+	}
+
+	v := to.Float64(dp.ScaledValue(val))
+	if len(dp.MinWrite) > 0 && v < to.Float64(dp.MinWrite) {
+		return fmt.Errorf("%s is below the minimum %s%s", dp.fmtValue(val), dp.MinWrite, dp.Unit)
+	}
+
+	if len(dp.MaxWrite) > 0 && v > to.Float64(dp.MaxWrite) {
+		return fmt.Errorf("%s is above the maximum %s%s", dp.fmtValue(val), dp.MaxWrite, dp.Unit)
+	}
+
+	return nil
+}
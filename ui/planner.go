@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/simonvetter/modbus"
+)
+
+const (
+	// maxRegistersPerRead is the maximum number of registers
+	// which can be read with a single request.
+	maxRegistersPerRead = 125
+
+	// maxBitsPerRead is the maximum number of coils or discrete
+	// inputs which can be read with a single request.
+	maxBitsPerRead = 2000
+)
+
+// readBlock represents a range of registers or bits of
+// a slave, which are read with a single request.
+type readBlock struct {
+	Connection string
+	SlaveId    uint8
+	Space      Space
+	Addr       uint16
+	Quantity   uint16
+	Datapoints []*Datapoint
+}
+
+// end returns the address after the last register of the block.
+func (b *readBlock) end() int {
+	return int(b.Addr) + int(b.Quantity)
+}
+
+// quantity returns the number of registers or bits of a datapoint.
+func quantity(dp *Datapoint) uint16 {
+	if dp.Space.IsBit() {
+		return 1
+	}
+	return dp.codec().registerCount()
+}
+
+// planReads groups datapoints by connection, slave id and register
+// space into blocks of contiguous registers. Datapoints which are
+// separated by at most maxGap unused registers are read in the same
+// block.
+func planReads(datapoints []*Datapoint, maxGap uint16) []*readBlock {
+	sorted := slices.Clone(datapoints)
+	slices.SortStableFunc(sorted, func(a, b *Datapoint) int {
+		return cmp.Or(
+			cmp.Compare(a.Connection, b.Connection),
+			cmp.Compare(a.SlaveId, b.SlaveId),
+			cmp.Compare(a.Space, b.Space),
+			cmp.Compare(a.Addr, b.Addr),
+		)
+	})
+
+	var blocks []*readBlock
+	var b *readBlock
+	for _, dp := range sorted {
+		limit := maxRegistersPerRead
+		if dp.Space.IsBit() {
+			limit = maxBitsPerRead
+		}
+
+		end := int(dp.Addr) + int(quantity(dp))
+		if b != nil &&
+			b.Connection == dp.Connection &&
+			b.SlaveId == dp.SlaveId &&
+			b.Space == dp.Space &&
+			int(dp.Addr) <= b.end()+int(maxGap) &&
+			max(end, b.end())-int(b.Addr) <= limit {
+			b.Quantity = uint16(max(end, b.end()) - int(b.Addr))
+			b.Datapoints = append(b.Datapoints, dp)
+			continue
+		}
+
+		b = &readBlock{
+			Connection: dp.Connection,
+			SlaveId:    dp.SlaveId,
+			Space:      dp.Space,
+			Addr:       dp.Addr,
+			Quantity:   quantity(dp),
+			Datapoints: []*Datapoint{dp},
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
+
+// ReadDatapoints reads the datapoints and sets their value
+// or error. Datapoints which are separated by at most maxGap
+// unused registers are read with a single request.
+func ReadDatapoints(client Client, datapoints []*Datapoint, maxGap uint16) {
+	readBlocks(client, planReads(datapoints, maxGap), func(dp *Datapoint, val any, err error) {
+		dp.Value = val
+		dp.Err = err
+	})
+}
+
+// readBlocks reads the blocks and calls fn with the value of each datapoint.
+// If a block of multiple datapoints fails with an exception of the server,
+// e.g. because an address isn't mapped, the datapoints are read one by one,
+// so that errors are reported for the right datapoints. Other errors, like
+// timeouts, are reported for all datapoints of the block.
+func readBlocks(client Client, blocks []*readBlock, fn func(dp *Datapoint, val any, err error)) {
+	for _, b := range blocks {
+		err := readBlockValues(client, b, fn)
+		if err == nil {
+			continue
+		}
+
+		if len(b.Datapoints) == 1 || !splitsBlock(err) {
+			for _, dp := range b.Datapoints {
+				fn(dp, nil, err)
+			}
+			continue
+		}
+
+		for _, dp := range b.Datapoints {
+			readBlocks(client, planReads([]*Datapoint{dp}, 0), fn)
+		}
+	}
+}
+
+// splitsBlock returns true if the datapoints of a block which failed
+// with the error are read one by one. Gateway exceptions concern all
+// datapoints of a server and are not split.
+func splitsBlock(err error) bool {
+	switch {
+	case errors.Is(err, modbus.ErrGWPathUnavailable),
+		errors.Is(err, modbus.ErrGWTargetFailedToRespond):
+		return false
+	}
+	return IsException(err)
+}
+
+// readBlockValues reads a block and decodes the value
+// of each datapoint from the read registers.
+func readBlockValues(client Client, b *readBlock, fn func(dp *Datapoint, val any, err error)) error {
+	client.SetUnitId(b.SlaveId)
+
+	switch b.Space {
+	case SpaceCoil, SpaceDiscreteInput:
+		var bits []bool
+		var err error
+		if b.Space == SpaceCoil {
+			bits, err = client.ReadCoils(b.Addr, b.Quantity)
+		} else {
+			bits, err = client.ReadDiscreteInputs(b.Addr, b.Quantity)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, dp := range b.Datapoints {
+			fn(dp, bits[dp.Addr-b.Addr], nil)
+		}
+	default:
+		regs, err := client.ReadRegisters(b.Addr, b.Quantity, b.Space.regType())
+		if err != nil {
+			return err
+		}
+
+		for _, dp := range b.Datapoints {
+			i := dp.Addr - b.Addr
+			fn(dp, dp.codec().decodeRegisters(regs[i:i+quantity(dp)]), nil)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/simonvetter/modbus"
+)
+
+// register returns a datapoint of a uint16 holding register.
+func register(slaveId uint8, addr uint16) *Datapoint {
+	return &Datapoint{SlaveId: slaveId, Addr: addr, Space: SpaceHoldingRegister, DataType: DataTypeUint16}
+}
+
+// coil returns a datapoint of a coil.
+func coil(slaveId uint8, addr uint16) *Datapoint {
+	return &Datapoint{SlaveId: slaveId, Addr: addr, Space: SpaceCoil, DataType: DataTypeBool}
+}
+
+// blockStrings returns the blocks as strings, e.g. "gateway 1 HR 0+3".
+// The connection is omitted for the default connection.
+func blockStrings(blocks []*readBlock) []string {
+	var strs []string
+	for _, b := range blocks {
+		str := fmt.Sprintf("%s %d %s %d+%d", b.Connection, b.SlaveId, b.Space, b.Addr, b.Quantity)
+		strs = append(strs, strings.TrimSpace(str))
+	}
+	return strs
+}
+
+func TestPlanReads(t *testing.T) {
+	uint32Register := register(1, 1)
+	uint32Register.DataType = DataTypeUint32
+
+	inputRegister := register(1, 0)
+	inputRegister.Space = SpaceInputRegister
+
+	lastRegister := register(1, 124)
+	lastRegister.DataType = DataTypeUint32
+
+	gateway := register(1, 1)
+	gateway.Connection = "gateway"
+
+	tests := []struct {
+		name       string
+		datapoints []*Datapoint
+		maxGap     uint16
+		blocks     []string
+	}{
+		{"contiguous", []*Datapoint{register(1, 0), uint32Register}, 0, []string{"1 HR 0+3"}},
+		{"unsorted", []*Datapoint{register(1, 2), register(1, 0), register(1, 1)}, 0, []string{"1 HR 0+3"}},
+		{"overlapping", []*Datapoint{uint32Register, register(1, 2)}, 0, []string{"1 HR 1+2"}},
+		{"gap", []*Datapoint{register(1, 0), register(1, 5)}, 4, []string{"1 HR 0+6"}},
+		{"gap too large", []*Datapoint{register(1, 0), register(1, 5)}, 3, []string{"1 HR 0+1", "1 HR 5+1"}},
+		{"register limit", []*Datapoint{register(1, 0), register(1, 124)}, 200, []string{"1 HR 0+125"}},
+		{"register limit exceeded", []*Datapoint{register(1, 0), lastRegister}, 200, []string{"1 HR 0+1", "1 HR 124+2"}},
+		{"bit limit", []*Datapoint{coil(1, 0), coil(1, 1999)}, 2000, []string{"1 Coil 0+2000"}},
+		{"bit limit exceeded", []*Datapoint{coil(1, 0), coil(1, 2000)}, 2000, []string{"1 Coil 0+1", "1 Coil 2000+1"}},
+		{"spaces", []*Datapoint{register(1, 0), inputRegister, coil(1, 1)}, 10, []string{"1 HR 0+1", "1 IR 0+1", "1 Coil 1+1"}},
+		{"unit ids", []*Datapoint{register(2, 1), register(1, 0)}, 10, []string{"1 HR 0+1", "2 HR 1+1"}},
+		{"connections", []*Datapoint{gateway, register(1, 0)}, 10, []string{"1 HR 0+1", "gateway 1 HR 1+1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := blockStrings(planReads(test.datapoints, test.maxGap))
+			if !slices.Equal(blocks, test.blocks) {
+				t.Fatalf("%q != %q", blocks, test.blocks)
+			}
+		})
+	}
+}
+
+func TestReadBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		unmapped uint16
+		err      error
+		requests []string
+	}{
+		{"mapped", 100, nil, []string{"ReadRegisters 0"}},
+		{"unmapped gap", 1, nil, []string{"ReadRegisters 0", "ReadRegisters 0", "ReadRegisters 2"}},
+		{"unmapped datapoint", 2, modbus.ErrIllegalDataAddress, []string{"ReadRegisters 0", "ReadRegisters 0", "ReadRegisters 2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.regs[0] = 1
+			client.regs[2] = 3
+			client.unmapped[test.unmapped] = true
+
+			datapoints := []*Datapoint{register(1, 0), register(1, 2)}
+			ReadDatapoints(client, datapoints, 1)
+
+			if !slices.Equal(client.requests, test.requests) {
+				t.Fatalf("%v != %v", client.requests, test.requests)
+			}
+
+			// The first datapoint is read despite the unmapped address
+			if dp := datapoints[0]; dp.Err != nil || dp.Value != uint16(1) {
+				t.Fatalf("%v, %v", dp.Value, dp.Err)
+			}
+
+			if dp := datapoints[1]; dp.Err != test.err {
+				t.Fatalf("%v != %v", dp.Err, test.err)
+			}
+		})
+	}
+}
+
+func TestReadBlocksGatewayException(t *testing.T) {
+	client := &gatewayClient{fakeClient: newFakeClient()}
+	datapoints := []*Datapoint{register(1, 0), register(1, 1)}
+	ReadDatapoints(client, datapoints, 0)
+
+	// Gateway exceptions are reported for the whole block
+	if !slices.Equal(client.requests, []string{"ReadRegisters 0"}) {
+		t.Fatalf("%v", client.requests)
+	}
+
+	for _, dp := range datapoints {
+		if dp.Err != modbus.ErrGWTargetFailedToRespond {
+			t.Fatalf("%v != %v", dp.Err, modbus.ErrGWTargetFailedToRespond)
+		}
+	}
+}
+
+// gatewayClient fails to read registers because
+// the target device of the gateway doesn't respond.
+type gatewayClient struct {
+	*fakeClient
+}
+
+func (c *gatewayClient) ReadRegisters(addr uint16, quantity uint16, regType modbus.RegType) ([]uint16, error) {
+	c.record("ReadRegisters %d", addr)
+	return nil, modbus.ErrGWTargetFailedToRespond
+}
@@ -0,0 +1,362 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/simonvetter/modbus"
+)
+
+// ReadResultMsg is sent when a datapoint was read.
+type ReadResultMsg struct {
+	Datapoint *Datapoint
+	Value     any
+	Err       error
+}
+
+// apply sets the value or error of the datapoint.
+func (msg ReadResultMsg) apply() {
+	dp := msg.Datapoint
+	dp.Reading = false
+	dp.Value = msg.Value
+	dp.Err = msg.Err
+
+	// The mismatch of a write is resolved once
+	// the written value is read
+	if dp.Written != nil && msg.Err == nil && dp.codec().equalValues(msg.Value, dp.Written) {
+		dp.Written = nil
+	}
+}
+
+// WriteResultMsg is sent when a value was written to a datapoint.
+type WriteResultMsg struct {
+	Datapoint *Datapoint
+	Value     any
+	Err       error
+
+	// Old is the value of the datapoint before writing,
+	// or nil if it couldn't be read.
+	Old any
+
+	// ReadBack is the value which was read back after writing.
+	// It differs from Value if the device didn't accept the
+	// value as it is, e.g. clamped it.
+	ReadBack any
+
+	// ReadBackErr is not-nil if reading back failed.
+	ReadBackErr error
+
+	// Pulse is the duration after which the datapoint is switched
+	// off again, if the write switched it on to start a pulse.
+	Pulse time.Duration
+}
+
+// Mismatch returns true if the value which
+// was read back differs from the written value.
+func (msg WriteResultMsg) Mismatch() bool {
+	return msg.Err == nil && msg.ReadBackErr == nil && !msg.Datapoint.codec().equalValues(msg.ReadBack, msg.Value)
+}
+
+// HistoryEntry returns the entry of the write in the history.
+func (msg WriteResultMsg) HistoryEntry() HistoryEntry {
+	var err error
+	switch {
+	case msg.Err != nil:
+		err = msg.Err
+	case msg.ReadBackErr != nil:
+		err = fmt.Errorf("reading back failed: %w", msg.ReadBackErr)
+	}
+
+	return NewHistoryEntry(msg.Datapoint, msg.Old, msg.Value, msg.ReadBack, err)
+}
+
+// pollRequest is either a request to read blocks
+// or to write a value to a datapoint.
+type pollRequest struct {
+	blocks []*readBlock
+
+	write *Datapoint
+	value any
+	pulse time.Duration
+
+	scan  *ScanRange
+	units *UnitProbe
+	ctx   context.Context
+}
+
+// Poller performs modbus requests on a background goroutine
+// and sends the results as messages, so that the user interface
+// is not blocked by slow or unreachable devices.
+type Poller struct {
+	client   Client
+	requests chan pollRequest
+
+	// conf is the configuration of the client, which is
+	// needed to probe units with a different timeout.
+	conf *modbus.ClientConfiguration
+
+	results chan tea.Msg
+	done    chan struct{}
+
+	// connections are the pollers of named connections, which
+	// share the results of the poller. Nil if the poller sends
+	// the requests of all connections with its client.
+	connections map[string]*Poller
+}
+
+// NewPoller returns a poller which sends requests with client.
+// Failed requests are retried the given number of times.
+// If the connection is broken, the client reconnects and
+// a LinkStateMsg is sent.
+func NewPoller(client *modbus.ModbusClient, retries uint) *Poller {
+	p := newPoller(nil)
+	p.client = p.newConnection("", client, retries)
+	p.connections = map[string]*Poller{}
+
+	return p
+}
+
+// Attach adds a named connection. Requests of datapoints which
+// refer to the connection are sent with client on a separate
+// goroutine, so that connections are polled concurrently while
+// the requests of a connection are serialized. The results are
+// received with Listen. The client is opened before the first
+// request and closed when the poller is stopped. Attach must be
+// called from the goroutine which calls Read and Write.
+func (p *Poller) Attach(name string, client *modbus.ModbusClient, conf *modbus.ClientConfiguration, retries uint) {
+	a := &Poller{
+		requests: make(chan pollRequest),
+		conf:     conf,
+		results:  p.results,
+		done:     p.done,
+	}
+	c := a.newConnection(name, client, retries)
+	c.closed = true
+	a.client = c
+	go func() {
+		a.run()
+		c.close()
+	}()
+
+	p.connections[name] = a
+}
+
+// newConnection returns a connection whose link state
+// messages refer to the connection with the name.
+func (p *Poller) newConnection(name string, client *modbus.ModbusClient, retries uint) *connection {
+	return &connection{
+		ModbusClient: client,
+		retries:      retries,
+		notify: func(msg LinkStateMsg) {
+			msg.Connection = name
+			p.send(msg)
+		},
+	}
+}
+
+// poller returns the poller of the connection with the name,
+// or nil if there is no such connection.
+func (p *Poller) poller(name string) *Poller {
+	if name == "" || p.connections == nil {
+		return p
+	}
+	return p.connections[name]
+}
+
+// newPoller returns a poller which sends requests with client.
+func newPoller(client Client) *Poller {
+	p := &Poller{
+		client:   client,
+		requests: make(chan pollRequest),
+		results:  make(chan tea.Msg),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+
+	return p
+}
+
+// Stop stops the poller. Pending requests are discarded.
+func (p *Poller) Stop() {
+	close(p.done)
+}
+
+// Read returns a command which queues requests to read the blocks
+// with the pollers of their connections. Datapoints of unknown
+// connections fail with an error.
+func (p *Poller) Read(blocks []*readBlock) tea.Cmd {
+	byConnection := map[string][]*readBlock{}
+	for _, b := range blocks {
+		byConnection[b.Connection] = append(byConnection[b.Connection], b)
+	}
+
+	// The pollers are looked up before the command is
+	// returned, because Attach changes the connections
+	pollers := make(map[string]*Poller, len(byConnection))
+	for name := range byConnection {
+		pollers[name] = p.poller(name)
+	}
+
+	return func() tea.Msg {
+		for name, blocks := range byConnection {
+			c := pollers[name]
+			if c == nil {
+				go p.fail(blocks, unknownConnectionError(name))
+				continue
+			}
+			go c.queue(pollRequest{blocks: blocks})()
+		}
+		return nil
+	}
+}
+
+// Write returns a command which queues a request to write the
+// value to the datapoint with the poller of its connection.
+func (p *Poller) Write(dp *Datapoint, val any) tea.Cmd {
+	return p.write(pollRequest{write: dp, value: val})
+}
+
+// Pulse returns a command which queues a request to switch the
+// datapoint on. The WriteResultMsg of the request contains the
+// duration, after which the datapoint has to be switched off.
+func (p *Poller) Pulse(dp *Datapoint, d time.Duration) tea.Cmd {
+	return p.write(pollRequest{write: dp, value: true, pulse: d})
+}
+
+func (p *Poller) write(req pollRequest) tea.Cmd {
+	dp := req.write
+	c := p.poller(dp.Connection)
+	if c == nil {
+		msg := WriteResultMsg{Datapoint: dp, Value: req.value, Pulse: req.pulse}
+		msg.Err = unknownConnectionError(dp.Connection)
+		return func() tea.Msg {
+			return msg
+		}
+	}
+
+	return c.queue(req)
+}
+
+// fail sends a ReadResultMsg with the error
+// for every datapoint of the blocks.
+func (p *Poller) fail(blocks []*readBlock, err error) {
+	for _, b := range blocks {
+		for _, dp := range b.Datapoints {
+			p.send(ReadResultMsg{Datapoint: dp, Err: err})
+		}
+	}
+}
+
+func unknownConnectionError(name string) error {
+	return fmt.Errorf(`unknown connection "%s"`, name)
+}
+
+// Scan returns a command which queues a request to scan the range.
+// A ScanResultMsg is sent for every address and a ScanDoneMsg
+// when the scan finished or ctx was canceled.
+func (p *Poller) Scan(ctx context.Context, r *ScanRange) tea.Cmd {
+	return p.queue(pollRequest{scan: r, ctx: ctx})
+}
+
+// ProbeUnits returns a command which queues a request to probe units.
+// A UnitResultMsg is sent for every unit id and a UnitScanDoneMsg
+// when probing finished or ctx was canceled.
+func (p *Poller) ProbeUnits(ctx context.Context, probe *UnitProbe) tea.Cmd {
+	return p.queue(pollRequest{units: probe, ctx: ctx})
+}
+
+// Listen returns a command which waits for the next result.
+// The command has to be returned again after every result.
+func (p *Poller) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-p.results:
+			return msg
+		case <-p.done:
+			return nil
+		}
+	}
+}
+
+func (p *Poller) queue(req pollRequest) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case p.requests <- req:
+		case <-p.done:
+		}
+		return nil
+	}
+}
+
+func (p *Poller) run() {
+	for {
+		select {
+		case req := <-p.requests:
+			p.handle(req)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *Poller) handle(req pollRequest) {
+	if req.units != nil {
+		err := p.probeUnits(req.ctx, *req.units, func(res UnitResult) {
+			p.send(UnitResultMsg{Probe: req.units, Result: res})
+		})
+		p.send(UnitScanDoneMsg{Probe: req.units, Err: err})
+		return
+	}
+
+	if req.scan != nil {
+		Scan(req.ctx, p.client, *req.scan, func(res ScanResult) {
+			p.send(ScanResultMsg{Range: req.scan, Result: res})
+		})
+		p.send(ScanDoneMsg{Range: req.scan})
+		return
+	}
+
+	if req.write != nil {
+		// The old value is read right before writing, because the
+		// last polled value may be outdated. Devices may silently
+		// clamp values, which is detected by reading back the
+		// written value
+		msg := WriteResultMsg{Datapoint: req.write, Value: req.value, Pulse: req.pulse}
+		msg.Old, _ = readBack(p.client, req.write)
+		msg.Err = WriteDatapoint(p.client, req.write, req.value)
+		if msg.Err == nil {
+			msg.ReadBack, msg.ReadBackErr = readBack(p.client, req.write)
+		}
+		p.send(msg)
+		return
+	}
+
+	readBlocks(p.client, req.blocks, func(dp *Datapoint, val any, err error) {
+		p.send(ReadResultMsg{Datapoint: dp, Value: val, Err: err})
+	})
+}
+
+// probeUnits sends the probe with a client whose requests time
+// out after the timeout of the probe. Failed requests are retried
+// as often as requests of the connection.
+func (p *Poller) probeUnits(ctx context.Context, probe UnitProbe, fn func(UnitResult)) error {
+	c, ok := p.client.(*connection)
+	if !ok || p.conf == nil {
+		probeUnits(ctx, probe, probe.sender(p.client), fn)
+		return nil
+	}
+
+	probe.Retries = c.retries
+	return c.suspend(func() error {
+		return ProbeUnits(ctx, *p.conf, probe, fn)
+	})
+}
+
+func (p *Poller) send(msg tea.Msg) {
+	select {
+	case p.results <- msg:
+	case <-p.done:
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/simonvetter/modbus"
+)
+
+func TestPollerReadWhileAttaching(t *testing.T) {
+	p := newPoller(nil)
+	p.connections = map[string]*Poller{}
+	defer p.Stop()
+
+	dp := &Datapoint{Connection: "gateway"}
+	cmd := p.Read([]*readBlock{{Connection: "gateway", Datapoints: []*Datapoint{dp}}})
+
+	client, err := modbus.NewClient(&modbus.ClientConfiguration{URL: "tcp://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection is unknown when Read is called,
+	// even if it's attached before the command runs
+	done := make(chan struct{})
+	go func() {
+		cmd()
+		close(done)
+	}()
+	p.Attach("gateway", client, nil, 0)
+	<-done
+
+	msg, ok := p.Listen()().(ReadResultMsg)
+	if !ok || msg.Datapoint != dp || msg.Err == nil {
+		t.Fatalf("unexpected message %v", msg)
+	}
+}
+
+func TestPollerWriteReadsOldValue(t *testing.T) {
+	client := newFakeClient()
+	client.regs[5] = 3
+
+	p := newPoller(client)
+	defer p.Stop()
+
+	// The last polled value is outdated
+	dp := &Datapoint{SlaveId: 1, Addr: 5, Space: SpaceHoldingRegister, Flag: FlagReadWrite, DataType: DataTypeUint16, Value: uint16(1)}
+	p.Write(dp, uint16(7))()
+
+	msg, ok := p.Listen()().(WriteResultMsg)
+	if !ok || msg.Err != nil {
+		t.Fatalf("unexpected message %v", msg)
+	}
+	if msg.Old != uint16(3) || msg.ReadBack != uint16(7) {
+		t.Fatalf("old %v, read back %v", msg.Old, msg.ReadBack)
+	}
+}
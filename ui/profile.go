@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/huh"
+)
+
+// defaultConnectionName is the name which is shown
+// for the default connection of a profile.
+const defaultConnectionName = "default"
+
+// Profile is a named modbus connection with its datapoints.
+type Profile struct {
+	Name       string               `json:"name"`
+	Modbus     *ModbusConfiguration `json:"modbus"`
+	Datapoints []*Datapoint         `json:"datapoints"`
+
+	// Connections are additional connections, e.g. to other
+	// gateways, which datapoints refer to by name.
+	Connections []*Connection `json:"connections,omitempty"`
+
+	// ReadOnly disables writing values to the devices of the profile.
+	ReadOnly bool `json:"read-only,omitempty"`
+}
+
+// Connection is a named modbus connection of a profile.
+type Connection struct {
+	Name   string               `json:"name"`
+	Modbus *ModbusConfiguration `json:"modbus"`
+}
+
+// FindConnection returns the connection with the name.
+func FindConnection(connections []*Connection, name string) *Connection {
+	for _, c := range connections {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// connectionName returns the name of the
+// connection which is shown to the user.
+func connectionName(name string) string {
+	if name == "" {
+		return defaultConnectionName
+	}
+	return name
+}
+
+// NewProfile returns an empty profile with the name.
+func NewProfile(name string) *Profile {
+	return &Profile{
+		Name:       name,
+		Modbus:     &ModbusConfiguration{},
+		Datapoints: []*Datapoint{},
+	}
+}
+
+// FindProfile returns the profile with the name.
+func FindProfile(profiles []*Profile, name string) *Profile {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// PromptProfile lets the user select a profile or create a new one,
+// which is appended to profiles. The profile with the name is
+// selected initially.
+func PromptProfile(profiles *[]*Profile, name string) (*Profile, error) {
+	// nil represents a new profile
+	selected := FindProfile(*profiles, name)
+
+	options := make([]huh.Option[*Profile], 0, len(*profiles)+1)
+	for _, p := range *profiles {
+		desc := p.Modbus.Description()
+		if p.ReadOnly {
+			desc += ", read-only"
+		}
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%s)", p.Name, desc), p))
+	}
+	options = append(options, huh.NewOption[*Profile]("New profile…", nil))
+
+	var newName string
+	var readOnly bool
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[*Profile]().
+				Title("Choose a profile").
+				Options(options...).
+				Value(&selected),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Enter the name of the new profile").
+				Validate(func(s string) error {
+					if len(s) == 0 {
+						return errors.New("name required")
+					}
+
+					if FindProfile(*profiles, s) != nil {
+						return fmt.Errorf(`profile "%s" already exists`, s)
+					}
+
+					return nil
+				}).
+				Value(&newName),
+
+			huh.NewConfirm().
+				Title("Read-only").
+				Description("Disable writing values to devices").
+				Affirmative("Yes").
+				Negative("No").
+				Value(&readOnly),
+		).
+			WithHideFunc(func() bool {
+				return selected != nil
+			}),
+	).Run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if selected == nil {
+		selected = NewProfile(newName)
+		selected.ReadOnly = readOnly
+		*profiles = append(*profiles, selected)
+	}
+
+	return selected, nil
+}
+
+// promptConnection lets the user enter the name and
+// configuration of a new connection.
+func promptConnection(connections []*Connection) (*Connection, error) {
+	c := &Connection{Modbus: &ModbusConfiguration{}}
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Enter the name of the new connection").
+				Validate(func(s string) error {
+					if len(s) == 0 {
+						return errors.New("name required")
+					}
+
+					if s == defaultConnectionName || FindConnection(connections, s) != nil {
+						return fmt.Errorf(`connection "%s" already exists`, s)
+					}
+
+					return nil
+				}).
+				Value(&c.Name),
+		),
+	).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := PromptConfig(c.Modbus); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
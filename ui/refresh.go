@@ -1,17 +1,182 @@
 package ui
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
 )
 
 type RefreshTickMsg struct {
+	seq int
 }
 
 // refreshTickMsg sends a RefreshTickMsg message after a duration.
-func refreshTickMsg(duration time.Duration) tea.Cmd {
+func refreshTickMsg(duration time.Duration, seq int) tea.Cmd {
 	return tea.Tick(duration, func(_ time.Time) tea.Msg {
-		return RefreshTickMsg{}
+		return RefreshTickMsg{seq}
 	})
 }
+
+// pollInterval returns the auto reload interval of a datapoint.
+// If the datapoint doesn't specify a poll interval, interval is returned.
+func pollInterval(dp *Datapoint, interval time.Duration) time.Duration {
+	if dp.PollInterval > 0 {
+		return time.Duration(dp.PollInterval)
+	}
+	return interval
+}
+
+// tickInterval returns the shortest poll interval of the datapoints.
+func tickInterval(datapoints []*Datapoint, interval time.Duration) time.Duration {
+	d := interval
+	for _, dp := range datapoints {
+		d = min(d, pollInterval(dp, interval))
+	}
+	return d
+}
+
+// dueDatapoints returns the datapoints whose poll interval has
+// elapsed. The returned datapoints are marked as being read.
+func dueDatapoints(datapoints []*Datapoint, interval time.Duration) []*Datapoint {
+	now := time.Now()
+	tolerance := tickInterval(datapoints, interval) / 2
+	return startReading(datapoints, func(dp *Datapoint) bool {
+		return dp.lastRead.IsZero() || now.Sub(dp.lastRead)+tolerance >= pollInterval(dp, interval)
+	})
+}
+
+// startReading returns the datapoints, which are not already
+// being read and for which due returns true, and marks them
+// as being read.
+func startReading(datapoints []*Datapoint, due func(dp *Datapoint) bool) []*Datapoint {
+	now := time.Now()
+	var res []*Datapoint
+	for _, dp := range datapoints {
+		if dp.Reading || !due(dp) {
+			continue
+		}
+		dp.Reading = true
+		dp.lastRead = now
+		res = append(res, dp)
+	}
+
+	return res
+}
+
+// Duration is a time.Duration which is stored
+// as a string (e.g. "1m30s") in json.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	if d == 0 {
+		return ""
+	}
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if len(s) == 0 {
+		*d = 0
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// DurationAccessor accesses a duration as a string.
+type DurationAccessor struct {
+	val *Duration
+}
+
+func NewDurationAccessor(val *Duration) *DurationAccessor {
+	return &DurationAccessor{val}
+}
+
+func (a *DurationAccessor) Get() string {
+	return a.val.String()
+}
+
+func (a *DurationAccessor) Set(value string) {
+	if len(value) == 0 {
+		*a.val = 0
+		return
+	}
+
+	v, err := time.ParseDuration(value)
+	if err == nil {
+		*a.val = Duration(v)
+	}
+}
+
+// validateDuration validates an optional duration.
+func validateDuration(s string) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("input not a duration (e.g. 500ms, 1m)")
+	}
+
+	if v < 0 {
+		return errors.New("duration must be positive")
+	}
+
+	return nil
+}
+
+// reloadIntervals are the selectable auto reload intervals.
+var reloadIntervals = []time.Duration{
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	1 * time.Minute,
+}
+
+// promptInterval prompts to select the auto reload interval.
+func promptInterval(interval time.Duration) (time.Duration, error) {
+	var opts []huh.Option[time.Duration]
+	for _, d := range reloadIntervals {
+		opts = append(opts, huh.NewOption(d.String(), d))
+	}
+
+	km := huh.NewDefaultKeyMap()
+	km.Quit.SetKeys("esc")
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[time.Duration]().
+				Title("Auto reload interval").
+				Options(opts...).
+				Value(&interval),
+		),
+	).
+		WithKeyMap(km).
+		Run()
+
+	return interval, err
+}
@@ -0,0 +1,292 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/goburrow/serial"
+	"github.com/simonvetter/modbus"
+)
+
+// fcReportServerId is the function code of Report Server ID requests.
+const fcReportServerId byte = 0x11
+
+// maxADUSize is the maximum size of a modbus frame.
+const maxADUSize = 260
+
+// errReportNotSupported is returned by clients
+// which can't send Report Server ID requests.
+var errReportNotSupported = errors.New("report server id not supported")
+
+// serverIdReporter is implemented by clients
+// which send Report Server ID requests.
+type serverIdReporter interface {
+	ReportServerId(unitId uint8) ([]byte, error)
+}
+
+// serverIdClient sends Report Server ID requests with its own
+// transport, because the modbus client doesn't support them.
+type serverIdClient struct {
+	conn    io.ReadWriteCloser
+	timeout time.Duration
+
+	// rtu is true if frames are sent with RTU framing,
+	// or false if they are sent with an MBAP header.
+	rtu bool
+
+	// packets is true if every response
+	// is received as a single datagram.
+	packets bool
+
+	txnId uint16
+}
+
+// dialServerIdClient opens a transport for the configuration.
+// The serial settings default to the ones of the modbus client.
+func dialServerIdClient(conf modbus.ClientConfiguration) (*serverIdClient, error) {
+	transport, addr, found := strings.Cut(conf.URL, "://")
+	if !found {
+		return nil, modbus.ErrConfigurationError
+	}
+
+	c := &serverIdClient{timeout: conf.Timeout}
+	if c.timeout <= 0 {
+		c.timeout = time.Second
+	}
+
+	var err error
+	switch transport {
+	case "tcp":
+		c.conn, err = net.DialTimeout("tcp", addr, c.timeout)
+	case "udp":
+		c.conn, err = net.DialTimeout("udp", addr, c.timeout)
+		c.packets = true
+	case "rtuovertcp":
+		c.conn, err = net.DialTimeout("tcp", addr, c.timeout)
+		c.rtu = true
+	case "rtuoverudp":
+		c.conn, err = net.DialTimeout("udp", addr, c.timeout)
+		c.rtu = true
+		c.packets = true
+	case "rtu":
+		c.conn, err = serial.Open(serialConfig(addr, conf))
+		c.rtu = true
+	default:
+		return nil, modbus.ErrConfigurationError
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// serialConfig returns the configuration of the serial port at addr.
+func serialConfig(addr string, conf modbus.ClientConfiguration) *serial.Config {
+	c := &serial.Config{
+		Address:  addr,
+		BaudRate: int(conf.Speed),
+		DataBits: int(conf.DataBits),
+		StopBits: int(conf.StopBits),
+		Parity:   "N",
+		Timeout:  conf.Timeout,
+	}
+
+	switch conf.Parity {
+	case modbus.PARITY_EVEN:
+		c.Parity = "E"
+	case modbus.PARITY_ODD:
+		c.Parity = "O"
+	}
+
+	if c.BaudRate == 0 {
+		c.BaudRate = 19200
+	}
+	if c.DataBits == 0 {
+		c.DataBits = 8
+	}
+	if c.StopBits == 0 {
+		c.StopBits = 1
+		if conf.Parity == modbus.PARITY_NONE {
+			c.StopBits = 2
+		}
+	}
+
+	return c
+}
+
+func (c *serverIdClient) Close() error {
+	return c.conn.Close()
+}
+
+// ReportServerId sends a Report Server ID request to the unit
+// and returns the data of the response, which contains the
+// device specific server id and the run indicator status.
+func (c *serverIdClient) ReportServerId(unitId uint8) ([]byte, error) {
+	if conn, ok := c.conn.(net.Conn); ok {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	var pdu []byte
+	var err error
+	if c.rtu {
+		pdu, err = c.exchangeRTU(unitId)
+	} else {
+		pdu, err = c.exchangeTCP(unitId)
+	}
+
+	var netErr net.Error
+	if errors.Is(err, serial.ErrTimeout) || errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, modbus.ErrRequestTimedOut
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return serverIdResponse(pdu)
+}
+
+// exchangeRTU sends the request with RTU framing
+// and returns the pdu of the response.
+func (c *serverIdClient) exchangeRTU(unitId uint8) ([]byte, error) {
+	req := []byte{unitId, fcReportServerId}
+	req = binary.LittleEndian.AppendUint16(req, crc16(req))
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	r, err := c.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	// The length of the response follows from the
+	// function code, or the byte count respectively
+	frame := make([]byte, 3, maxADUSize)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+
+	n := 2
+	if frame[1] == fcReportServerId {
+		n += int(frame[2])
+	}
+	frame = frame[:3+n]
+	if _, err := io.ReadFull(r, frame[3:]); err != nil {
+		return nil, err
+	}
+
+	if crc16(frame[:len(frame)-2]) != binary.LittleEndian.Uint16(frame[len(frame)-2:]) {
+		return nil, modbus.ErrBadCRC
+	}
+
+	if frame[0] != unitId {
+		return nil, modbus.ErrBadUnitId
+	}
+
+	return frame[1 : len(frame)-2], nil
+}
+
+// exchangeTCP sends the request with an MBAP header
+// and returns the pdu of the response.
+func (c *serverIdClient) exchangeTCP(unitId uint8) ([]byte, error) {
+	c.txnId++
+	req := binary.BigEndian.AppendUint16(nil, c.txnId)
+	req = append(req, 0, 0, 0, 2, unitId, fcReportServerId)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	r, err := c.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint16(header[4:])
+	if n < 2 || n > maxADUSize-6 {
+		return nil, modbus.ErrProtocolError
+	}
+
+	pdu := make([]byte, n-1)
+	if _, err := io.ReadFull(r, pdu); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case binary.BigEndian.Uint16(header) != c.txnId:
+		return nil, modbus.ErrBadTransactionId
+	case binary.BigEndian.Uint16(header[2:]) != 0:
+		return nil, modbus.ErrUnknownProtocolId
+	case header[6] != unitId:
+		return nil, modbus.ErrBadUnitId
+	}
+
+	return pdu, nil
+}
+
+// reader returns the reader of the response. Responses
+// over datagram transports are read as a whole, because
+// the rest of a datagram is discarded by partial reads.
+func (c *serverIdClient) reader() (io.Reader, error) {
+	if !c.packets {
+		return c.conn, nil
+	}
+
+	buf := make([]byte, maxADUSize)
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(buf[:n]), nil
+}
+
+// serverIdResponse returns the data of a Report Server
+// ID response pdu, or the exception of the response.
+func serverIdResponse(pdu []byte) ([]byte, error) {
+	switch {
+	case len(pdu) == 2 && pdu[0] == fcReportServerId|0x80:
+		return nil, exceptionError(pdu[1])
+	case len(pdu) < 2 || pdu[0] != fcReportServerId || int(pdu[1]) != len(pdu)-2:
+		return nil, modbus.ErrProtocolError
+	}
+
+	return pdu[2:], nil
+}
+
+// exceptionError returns the error of the modbus exception code.
+func exceptionError(code byte) error {
+	switch code {
+	case 0x01:
+		return modbus.ErrIllegalFunction
+	case 0x02:
+		return modbus.ErrIllegalDataAddress
+	case 0x03:
+		return modbus.ErrIllegalDataValue
+	case 0x04:
+		return modbus.ErrServerDeviceFailure
+	case 0x05:
+		return modbus.ErrAcknowledge
+	case 0x06:
+		return modbus.ErrServerDeviceBusy
+	case 0x08:
+		return modbus.ErrMemoryParityError
+	case 0x0a:
+		return modbus.ErrGWPathUnavailable
+	case 0x0b:
+		return modbus.ErrGWTargetFailedToRespond
+	}
+
+	return modbus.ErrProtocolError
+}
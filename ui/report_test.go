@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+// serveReport answers a single request of the connection with res,
+// which is framed with an MBAP header or RTU framing.
+func serveReport(t *testing.T, rtu bool, res func(unitId byte) []byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if rtu {
+			req := make([]byte, 4)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			frame := append([]byte{req[0]}, res(req[0])...)
+			conn.Write(binary.LittleEndian.AppendUint16(frame, crc16(frame)))
+			return
+		}
+
+		req := make([]byte, 8)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		pdu := res(req[6])
+		frame := append(req[:4:4], 0, byte(len(pdu)+1), req[6])
+		conn.Write(append(frame, pdu...))
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestReportServerId(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		res       []byte
+		id        []byte
+		err       error
+	}{
+		{"tcp", "tcp", []byte{0x11, 0x02, 0x2a, 0xff}, []byte{0x2a, 0xff}, nil},
+		{"tcp exception", "tcp", []byte{0x91, 0x01}, nil, modbus.ErrIllegalFunction},
+		{"rtu over tcp", "rtuovertcp", []byte{0x11, 0x03, 0x2a, 0x2b, 0x00}, []byte{0x2a, 0x2b, 0x00}, nil},
+		{"rtu over tcp exception", "rtuovertcp", []byte{0x91, 0x0b}, nil, modbus.ErrGWTargetFailedToRespond},
+		{"invalid byte count", "tcp", []byte{0x11, 0x03, 0x2a}, nil, modbus.ErrProtocolError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr := serveReport(t, test.transport != "tcp", func(byte) []byte { return test.res })
+			c, err := dialServerIdClient(modbus.ClientConfiguration{URL: test.transport + "://" + addr, Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			id, err := c.ReportServerId(7)
+			if err != test.err {
+				t.Fatalf("%v != %v", err, test.err)
+			}
+			if !bytes.Equal(id, test.id) {
+				t.Fatalf("% x != % x", id, test.id)
+			}
+		})
+	}
+}
+
+func TestReportServerIdTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c, err := dialServerIdClient(modbus.ClientConfiguration{URL: "tcp://" + ln.Addr().String(), Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.ReportServerId(1); err != modbus.ErrRequestTimedOut {
+		t.Fatalf("%v != %v", err, modbus.ErrRequestTimedOut)
+	}
+}
@@ -0,0 +1,359 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+// Function codes supported by the RTU over TCP server.
+const (
+	fcReadCoils              byte = 0x01
+	fcReadDiscreteInputs     byte = 0x02
+	fcReadHoldingRegisters   byte = 0x03
+	fcReadInputRegisters     byte = 0x04
+	fcWriteSingleCoil        byte = 0x05
+	fcWriteSingleRegister    byte = 0x06
+	fcWriteMultipleCoils     byte = 0x0f
+	fcWriteMultipleRegisters byte = 0x10
+)
+
+// rtuIdleTimeout is the duration after which idle clients are disconnected.
+const rtuIdleTimeout = 2 * time.Minute
+
+// rtuOverTCPServer is a modbus server which exchanges RTU frames
+// over TCP connections. The modbus library only serves TCP.
+type rtuOverTCPServer struct {
+	handler  modbus.RequestHandler
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+}
+
+// newRTUOverTCPServer returns a server which listens at addr.
+func newRTUOverTCPServer(addr string, handler modbus.RequestHandler) (*rtuOverTCPServer, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &rtuOverTCPServer{
+		handler:  handler,
+		listener: l,
+		conns:    map[net.Conn]bool{},
+	}
+	go s.accept()
+
+	return s, nil
+}
+
+// Stop closes the listener and all client connections.
+func (s *rtuOverTCPServer) Stop() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *rtuOverTCPServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+// serve handles the requests of a client until the connection is closed.
+func (s *rtuOverTCPServer) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(rtuIdleTimeout))
+		frame, err := readRTURequest(r)
+		if err != nil {
+			return
+		}
+
+		// Frames with an invalid checksum are ignored
+		if frame == nil {
+			continue
+		}
+
+		res := s.handle(frame[0], frame[1], frame[2:])
+		if res == nil {
+			continue
+		}
+
+		if _, err := conn.Write(appendCRC(res)); err != nil {
+			return
+		}
+	}
+}
+
+// readRTURequest reads a request frame and returns it without
+// the checksum. If the checksum is invalid, nil is returned.
+func readRTURequest(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	var n int
+	switch header[1] {
+	case fcReadCoils, fcReadDiscreteInputs, fcReadHoldingRegisters, fcReadInputRegisters,
+		fcWriteSingleCoil, fcWriteSingleRegister:
+		n = 4
+	case fcWriteMultipleCoils, fcWriteMultipleRegisters:
+		// addr, quantity and byte count precede the values
+		buf, err := r.Peek(5)
+		if err != nil {
+			return nil, err
+		}
+		n = 5 + int(buf[4])
+	default:
+		// The frame length of unsupported functions is unknown.
+		// Discard everything what was received so far.
+		r.Discard(r.Buffered())
+		return header, nil
+	}
+
+	frame := make([]byte, 2+n+2)
+	copy(frame, header)
+	if _, err := io.ReadFull(r, frame[2:]); err != nil {
+		return nil, err
+	}
+
+	if crc16(frame[:len(frame)-2]) != binary.LittleEndian.Uint16(frame[len(frame)-2:]) {
+		return nil, nil
+	}
+
+	return frame[:len(frame)-2], nil
+}
+
+// handle returns the response to a request. No response
+// is returned for requests to unknown unit ids.
+func (s *rtuOverTCPServer) handle(unitId byte, fc byte, data []byte) []byte {
+	payload, err := s.handleFunction(unitId, fc, data)
+	if err == modbus.ErrGWTargetFailedToRespond {
+		return nil
+	}
+
+	if err != nil {
+		return []byte{unitId, fc | 0x80, exceptionCode(err)}
+	}
+
+	return append([]byte{unitId, fc}, payload...)
+}
+
+// handleFunction returns the response payload to a request.
+func (s *rtuOverTCPServer) handleFunction(unitId byte, fc byte, data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, modbus.ErrIllegalFunction
+	}
+
+	addr := binary.BigEndian.Uint16(data[0:])
+	qty := binary.BigEndian.Uint16(data[2:])
+
+	switch fc {
+	case fcReadCoils, fcReadDiscreteInputs:
+		if qty == 0 || qty > maxBitsPerRead {
+			return nil, modbus.ErrIllegalDataValue
+		}
+
+		if !inRange(addr, qty) {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+
+		var bits []bool
+		var err error
+		if fc == fcReadCoils {
+			bits, err = s.handler.HandleCoils(&modbus.CoilsRequest{UnitId: unitId, Addr: addr, Quantity: qty})
+		} else {
+			bits, err = s.handler.HandleDiscreteInputs(&modbus.DiscreteInputsRequest{UnitId: unitId, Addr: addr, Quantity: qty})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return withByteCount(encodeBits(bits)), nil
+
+	case fcReadHoldingRegisters, fcReadInputRegisters:
+		if qty == 0 || qty > maxRegistersPerRead {
+			return nil, modbus.ErrIllegalDataValue
+		}
+
+		if !inRange(addr, qty) {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+
+		var regs []uint16
+		var err error
+		if fc == fcReadHoldingRegisters {
+			regs, err = s.handler.HandleHoldingRegisters(&modbus.HoldingRegistersRequest{UnitId: unitId, Addr: addr, Quantity: qty})
+		} else {
+			regs, err = s.handler.HandleInputRegisters(&modbus.InputRegistersRequest{UnitId: unitId, Addr: addr, Quantity: qty})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return withByteCount(encodeRegisters(regs)), nil
+
+	case fcWriteSingleCoil:
+		if qty != 0x0000 && qty != 0xff00 {
+			return nil, modbus.ErrIllegalDataValue
+		}
+
+		req := &modbus.CoilsRequest{UnitId: unitId, Addr: addr, Quantity: 1, IsWrite: true, Args: []bool{qty == 0xff00}}
+		if _, err := s.handler.HandleCoils(req); err != nil {
+			return nil, err
+		}
+
+		return data[:4], nil
+
+	case fcWriteSingleRegister:
+		req := &modbus.HoldingRegistersRequest{UnitId: unitId, Addr: addr, Quantity: 1, IsWrite: true, Args: []uint16{qty}}
+		if _, err := s.handler.HandleHoldingRegisters(req); err != nil {
+			return nil, err
+		}
+
+		return data[:4], nil
+
+	case fcWriteMultipleCoils:
+		if qty == 0 || qty > 0x7b0 || int(data[4]) != int(qty+7)/8 {
+			return nil, modbus.ErrIllegalDataValue
+		}
+
+		if !inRange(addr, qty) {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+
+		bits := make([]bool, qty)
+		for i := range bits {
+			bits[i] = data[5+i/8]&(1<<(i%8)) != 0
+		}
+
+		req := &modbus.CoilsRequest{UnitId: unitId, Addr: addr, Quantity: qty, IsWrite: true, Args: bits}
+		if _, err := s.handler.HandleCoils(req); err != nil {
+			return nil, err
+		}
+
+		return data[:4], nil
+
+	case fcWriteMultipleRegisters:
+		if qty == 0 || qty > 0x7b || int(data[4]) != int(qty)*2 {
+			return nil, modbus.ErrIllegalDataValue
+		}
+
+		if !inRange(addr, qty) {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+
+		regs := make([]uint16, qty)
+		for i := range regs {
+			regs[i] = binary.BigEndian.Uint16(data[5+i*2:])
+		}
+
+		req := &modbus.HoldingRegistersRequest{UnitId: unitId, Addr: addr, Quantity: qty, IsWrite: true, Args: regs}
+		if _, err := s.handler.HandleHoldingRegisters(req); err != nil {
+			return nil, err
+		}
+
+		return data[:4], nil
+	}
+
+	return nil, modbus.ErrIllegalFunction
+}
+
+// inRange returns true if qty addresses starting
+// at addr don't exceed the address 0xffff.
+func inRange(addr uint16, qty uint16) bool {
+	return uint32(addr)+uint32(qty) <= 0x10000
+}
+
+// exceptionCode returns the modbus exception code of err.
+func exceptionCode(err error) byte {
+	switch err {
+	case modbus.ErrIllegalFunction:
+		return 0x01
+	case modbus.ErrIllegalDataAddress:
+		return 0x02
+	case modbus.ErrIllegalDataValue:
+		return 0x03
+	case modbus.ErrAcknowledge:
+		return 0x05
+	case modbus.ErrServerDeviceBusy:
+		return 0x06
+	case modbus.ErrGWPathUnavailable:
+		return 0x0a
+	}
+
+	return 0x04
+}
+
+func withByteCount(buf []byte) []byte {
+	return append([]byte{byte(len(buf))}, buf...)
+}
+
+func encodeBits(bits []bool) []byte {
+	buf := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			buf[i/8] |= 1 << (i % 8)
+		}
+	}
+	return buf
+}
+
+func encodeRegisters(regs []uint16) []byte {
+	buf := make([]byte, len(regs)*2)
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(buf[i*2:], r)
+	}
+	return buf
+}
+
+// appendCRC appends the checksum to the frame.
+func appendCRC(frame []byte) []byte {
+	return binary.LittleEndian.AppendUint16(frame, crc16(frame))
+}
+
+// crc16 returns the modbus checksum of data.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
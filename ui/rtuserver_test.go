@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/simonvetter/modbus"
+)
+
+// zeroHandler responds to every request with zero values.
+type zeroHandler struct{}
+
+func (zeroHandler) HandleCoils(req *modbus.CoilsRequest) ([]bool, error) {
+	return make([]bool, req.Quantity), nil
+}
+
+func (zeroHandler) HandleDiscreteInputs(req *modbus.DiscreteInputsRequest) ([]bool, error) {
+	return make([]bool, req.Quantity), nil
+}
+
+func (zeroHandler) HandleHoldingRegisters(req *modbus.HoldingRegistersRequest) ([]uint16, error) {
+	return make([]uint16, req.Quantity), nil
+}
+
+func (zeroHandler) HandleInputRegisters(req *modbus.InputRegistersRequest) ([]uint16, error) {
+	return make([]uint16, req.Quantity), nil
+}
+
+func TestHandleFunctionRange(t *testing.T) {
+	tests := []struct {
+		name string
+		fc   byte
+		data []byte
+		err  error
+	}{
+		{"read last register", fcReadHoldingRegisters, []byte{0xff, 0xff, 0x00, 0x01}, nil},
+		{"read beyond last register", fcReadHoldingRegisters, []byte{0xff, 0xff, 0x00, 0x02}, modbus.ErrIllegalDataAddress},
+		{"read beyond last coil", fcReadCoils, []byte{0xff, 0xf0, 0x00, 0x20}, modbus.ErrIllegalDataAddress},
+		{"write single register", fcWriteSingleRegister, []byte{0xff, 0xff, 0x12, 0x34}, nil},
+		{"write single coil", fcWriteSingleCoil, []byte{0xff, 0xff, 0xff, 0x00}, nil},
+		{"write registers beyond last register", fcWriteMultipleRegisters, []byte{0xff, 0xff, 0x00, 0x02, 0x04, 0, 1, 0, 2}, modbus.ErrIllegalDataAddress},
+		{"write coils beyond last coil", fcWriteMultipleCoils, []byte{0xff, 0xff, 0x00, 0x02, 0x01, 0x03}, modbus.ErrIllegalDataAddress},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &rtuOverTCPServer{handler: zeroHandler{}}
+			if _, err := s.handleFunction(1, test.fc, test.data); err != test.err {
+				t.Fatalf("%v != %v", err, test.err)
+			}
+		})
+	}
+}
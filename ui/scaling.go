@@ -0,0 +1,312 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xiam/to"
+)
+
+// defaultDecimals is the number of decimals of scaled values.
+const defaultDecimals = 2
+
+// ScalingKind represents how the raw value of a datapoint is scaled.
+type ScalingKind byte
+
+const (
+	ScalingNone       ScalingKind = iota
+	ScalingLinear                 // maps an input range to an output range
+	ScalingFactor                 // raw * factor + offset
+	ScalingExpression             // arithmetic expression over the raw value
+)
+
+func (k ScalingKind) String() string {
+	switch k {
+	case ScalingLinear:
+		return "linear"
+	case ScalingFactor:
+		return "factor"
+	case ScalingExpression:
+		return "expression"
+	}
+
+	return "none"
+}
+
+type Scaling struct {
+	Kind ScalingKind `json:"kind"`
+
+	// MinIn, MaxIn, MinOut and MaxOut
+	// are the ranges of a linear scaling.
+	MinIn  string `json:"minIn,omitempty"`
+	MaxIn  string `json:"maxIn,omitempty"`
+	MinOut string `json:"minOut,omitempty"`
+	MaxOut string `json:"maxOut,omitempty"`
+
+	// Factor and Offset scale the raw value to raw * factor + offset.
+	// An empty factor is 1 and an empty offset is 0.
+	Factor string `json:"factor,omitempty"`
+	Offset string `json:"offset,omitempty"`
+
+	// Expression is an arithmetic expression over the
+	// raw value, e.g. "raw * 0.1 - 40".
+	Expression string `json:"expression,omitempty"`
+
+	// Decimals is the number of decimals of scaled values.
+	Decimals int `json:"decimals"`
+
+	// expr is the parsed expression, which is
+	// nil if the expression wasn't parsed yet.
+	expr expression
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Scalings stored without a kind only have min/max ranges
+// and are migrated to linear scalings with 2 decimals.
+// Ranges which map values to themselves are migrated
+// to no scaling.
+func (s *Scaling) UnmarshalJSON(data []byte) error {
+	type scaling Scaling
+	v := struct {
+		*scaling
+		Kind *ScalingKind `json:"kind"`
+	}{
+		scaling: (*scaling)(s),
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Kind != nil {
+		s.Kind = *v.Kind
+		s.compile()
+		return nil
+	}
+
+	s.Kind = ScalingNone
+	s.Decimals = defaultDecimals
+	if len(s.MinIn) > 0 && len(s.MaxIn) > 0 && len(s.MinOut) > 0 && len(s.MaxOut) > 0 {
+		minIn, maxIn, minOut, maxOut := s.Ranges()
+		if minIn != minOut || maxIn != maxOut {
+			s.Kind = ScalingLinear
+		}
+	}
+
+	return nil
+}
+
+func (s Scaling) Valid() bool {
+	switch s.Kind {
+	case ScalingLinear:
+		minIn, maxIn, _, _ := s.Ranges()
+		return len(s.MinIn) > 0 && len(s.MaxIn) > 0 && len(s.MinOut) > 0 && len(s.MaxOut) > 0 && minIn != maxIn
+	case ScalingFactor:
+		return true
+	case ScalingExpression:
+		_, err := s.expression()
+		return err == nil
+	}
+
+	return false
+}
+
+// compile parses the expression of an expression scaling once,
+// instead of every time a value is scaled. It has to be called
+// after the expression was changed.
+func (s *Scaling) compile() {
+	s.expr = nil
+	if s.Kind == ScalingExpression {
+		s.expr, _ = parseExpression(s.Expression)
+	}
+}
+
+// expression returns the parsed expression, which
+// is parsed if it wasn't compiled.
+func (s Scaling) expression() (expression, error) {
+	if s.expr != nil {
+		return s.expr, nil
+	}
+	return parseExpression(s.Expression)
+}
+
+func (s Scaling) Ranges() (minIn float64, maxIn float64, minOut float64, maxOut float64) {
+	minIn = to.Float64(s.MinIn)
+	maxIn = to.Float64(s.MaxIn)
+	minOut = to.Float64(s.MinOut)
+	maxOut = to.Float64(s.MaxOut)
+	return
+}
+
+// factor returns the factor and offset of a factor scaling.
+func (s Scaling) factor() (factor float64, offset float64) {
+	factor = 1
+	if len(s.Factor) > 0 {
+		factor = to.Float64(s.Factor)
+	}
+	return factor, to.Float64(s.Offset)
+}
+
+// apply returns the scaled raw value.
+func (s Scaling) apply(raw float64) float64 {
+	switch s.Kind {
+	case ScalingLinear:
+		minIn, maxIn, minOut, maxOut := s.Ranges()
+		inSize := math.Abs(minIn - maxIn)
+		outSize := math.Abs(minOut - maxOut)
+		return (raw-minIn)/inSize*outSize + minOut
+	case ScalingFactor:
+		factor, offset := s.factor()
+		return raw*factor + offset
+	case ScalingExpression:
+		if expr, err := s.expression(); err == nil {
+			return expr(raw)
+		}
+	}
+
+	return raw
+}
+
+// inverse returns the raw value of the scaled value. Expressions
+// are inverted if they are linear over the raw value.
+func (s Scaling) inverse(val float64) (float64, error) {
+	switch s.Kind {
+	case ScalingLinear:
+		minIn, maxIn, minOut, maxOut := s.Ranges()
+		inSize := math.Abs(minIn - maxIn)
+		outSize := math.Abs(minOut - maxOut)
+		if outSize == 0 {
+			return 0, errors.New("scaling can't be inverted")
+		}
+		return (val-minOut)/outSize*inSize + minIn, nil
+	case ScalingFactor:
+		factor, offset := s.factor()
+		if factor == 0 {
+			return 0, errors.New("scaling can't be inverted")
+		}
+		return (val - offset) / factor, nil
+	case ScalingExpression:
+		expr, err := s.expression()
+		if err != nil {
+			return 0, err
+		}
+
+		offset := expr(0)
+		slope := expr(1) - offset
+		raw := (val - offset) / slope
+		if slope == 0 || math.IsNaN(raw) || math.IsInf(raw, 0) || math.Abs(expr(raw)-val) > 1e-9*max(1, math.Abs(val)) {
+			return 0, fmt.Errorf(`expression "%s" can't be inverted`, s.Expression)
+		}
+		return raw, nil
+	}
+
+	return val, nil
+}
+
+// format returns the scaled value with the number of decimals.
+func (s Scaling) format(val float64) string {
+	return fmt.Sprintf("%.*f", max(s.Decimals, 0), val)
+}
+
+// validateExpression validates an expression.
+func validateExpression(s string) error {
+	if len(s) == 0 {
+		return errors.New("expression required")
+	}
+
+	_, err := parseExpression(s)
+	return err
+}
+
+// rawLimits returns the smallest and largest raw value of the
+// datapoint, and whether raw values are integers.
+func (dp Datapoint) rawLimits() (lo float64, hi float64, integer bool) {
+	switch dp.DataType {
+	case DataTypeUint16:
+		return 0, math.MaxUint16, true
+	case DataTypeUint32:
+		return 0, math.MaxUint32, true
+	case DataTypeUint64:
+		return 0, math.MaxUint64, true
+	case DataTypeInt16:
+		return math.MinInt16, math.MaxInt16, true
+	case DataTypeInt32:
+		return math.MinInt32, math.MaxInt32, true
+	case DataTypeInt64:
+		return math.MinInt64, math.MaxInt64, true
+	case DataTypeFloat32:
+		return -math.MaxFloat32, math.MaxFloat32, false
+	case DataTypeBits:
+		return 0, float64(dp.Mask.max()), true
+	}
+
+	return -math.MaxFloat64, math.MaxFloat64, false
+}
+
+// scalesOnWrite returns true if values written to the datapoint
+// are scaled values. Booleans, single bits and strings are
+// always written as they are.
+func (dp Datapoint) scalesOnWrite() bool {
+	if dp.Scaling == nil || !dp.Scaling.Valid() || dp.Space.IsBit() {
+		return false
+	}
+
+	switch dp.DataType {
+	case DataTypeBool, DataTypeCoil, DataTypeString:
+		return false
+	case DataTypeBits:
+		return !dp.Mask.single()
+	}
+
+	return true
+}
+
+// ParseScaledValue parses a scaled value (e.g. "21.5" or "21.5 °C")
+// and returns the raw value by applying the inverse of the scaling.
+// Values outside of the output range of the scaling, or which can't
+// be stored as raw value, are rejected. Datapoints without scaling
+// are parsed with ParseValue.
+func (dp Datapoint) ParseScaledValue(s string) (any, error) {
+	if !dp.scalesOnWrite() {
+		return dp.ParseValue(s)
+	}
+
+	s = strings.TrimSpace(s)
+	if len(dp.Unit) > 0 {
+		s = strings.TrimSpace(strings.TrimSuffix(s, strings.TrimSpace(dp.Unit)))
+	}
+
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid value "%s"`, s)
+	}
+
+	sc := dp.Scaling
+	if sc.Kind == ScalingLinear {
+		_, _, minOut, maxOut := sc.Ranges()
+		if val < min(minOut, maxOut) || val > max(minOut, maxOut) {
+			return nil, fmt.Errorf("%s is outside the range %s to %s", s, sc.format(min(minOut, maxOut)), sc.format(max(minOut, maxOut)))
+		}
+	}
+
+	raw, err := sc.inverse(val)
+	if err != nil {
+		return nil, err
+	}
+
+	lo, hi, integer := dp.rawLimits()
+	if integer {
+		raw = math.Round(raw)
+	}
+
+	if raw < lo || raw > hi {
+		a, b := sc.apply(lo), sc.apply(hi)
+		return nil, fmt.Errorf("%s is outside the range %s to %s", s, sc.format(min(a, b)), sc.format(max(a, b)))
+	}
+
+	return dp.ParseValue(strconv.FormatFloat(raw, 'f', -1, 64))
+}
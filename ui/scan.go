@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simonvetter/modbus"
+)
+
+// ScanRange represents the addresses of a slave
+// and register space which are scanned.
+type ScanRange struct {
+	SlaveId uint8
+	Space   Space
+	Start   uint16
+
+	// End is the last scanned address.
+	End uint16
+}
+
+// ScanResult is the result of reading an address during a scan.
+type ScanResult struct {
+	SlaveId uint8
+	Space   Space
+	Addr    uint16
+
+	// Value is the raw register value, or 0 and 1 for bits.
+	Value uint16
+
+	// Err is not-nil if the address could not be read.
+	Err error
+}
+
+// IsIllegalAddress returns true if the device
+// returned an illegal data address exception.
+func (r ScanResult) IsIllegalAddress() bool {
+	return r.Err == modbus.ErrIllegalDataAddress
+}
+
+// FormattedValue returns the value as string.
+func (r ScanResult) FormattedValue() string {
+	if r.Err != nil {
+		return ""
+	}
+
+	if r.Space.IsBit() {
+		return fmt.Sprintf("%t", r.Value != 0)
+	}
+	return fmt.Sprintf("%d (0x%04X)", r.Value, r.Value)
+}
+
+// Datapoint returns a new datapoint for the address.
+func (r ScanResult) Datapoint() *Datapoint {
+	dp := &Datapoint{
+		SlaveId:  r.SlaveId,
+		Name:     fmt.Sprintf("%s %d", r.Space, r.Addr),
+		Addr:     r.Addr,
+		Space:    r.Space,
+		DataType: DataTypeUint16,
+		Flag:     FlagRead,
+	}
+
+	if r.Space.IsBit() {
+		dp.DataType = DataTypeBool
+	}
+
+	return dp
+}
+
+// ScanResultMsg is sent when an address was scanned.
+type ScanResultMsg struct {
+	Range  *ScanRange
+	Result ScanResult
+}
+
+// ScanDoneMsg is sent when a scan finished or was canceled.
+type ScanDoneMsg struct {
+	Range *ScanRange
+}
+
+// Scan reads every address of the range and calls fn with the result.
+// Addresses are read in blocks, which are split into halves if the
+// device returns an exception, so that sparse register maps are
+// scanned with few requests. The scan stops when ctx is canceled.
+func Scan(ctx context.Context, client Client, r ScanRange, fn func(ScanResult)) {
+	limit := maxRegistersPerRead
+	if r.Space.IsBit() {
+		limit = maxBitsPerRead
+	}
+
+	for addr := int(r.Start); addr <= int(r.End); addr += limit {
+		if ctx.Err() != nil {
+			return
+		}
+
+		n := min(limit, int(r.End)-addr+1)
+		scanBlock(ctx, client, r, uint16(addr), uint16(n), fn)
+	}
+}
+
+func scanBlock(ctx context.Context, client Client, r ScanRange, addr uint16, quantity uint16, fn func(ScanResult)) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	values, err := readRaw(client, r.SlaveId, r.Space, addr, quantity)
+	if err != nil && IsException(err) && quantity > 1 {
+		half := quantity / 2
+		scanBlock(ctx, client, r, addr, half, fn)
+		scanBlock(ctx, client, r, addr+half, quantity-half, fn)
+		return
+	}
+
+	for i := uint16(0); i < quantity; i++ {
+		res := ScanResult{SlaveId: r.SlaveId, Space: r.Space, Addr: addr + i, Err: err}
+		if err == nil {
+			res.Value = values[i]
+		}
+		fn(res)
+	}
+}
+
+// readRaw reads registers or bits of a slave. Bits are returned as 0 or 1.
+func readRaw(client Client, slave uint8, space Space, addr uint16, quantity uint16) ([]uint16, error) {
+	client.SetUnitId(slave)
+
+	switch space {
+	case SpaceCoil:
+		bits, err := client.ReadCoils(addr, quantity)
+		return boolsToUint16s(bits), err
+	case SpaceDiscreteInput:
+		bits, err := client.ReadDiscreteInputs(addr, quantity)
+		return boolsToUint16s(bits), err
+	case SpaceInputRegister:
+		return client.ReadRegisters(addr, quantity, modbus.INPUT_REGISTER)
+	}
+
+	return client.ReadRegisters(addr, quantity, modbus.HOLDING_REGISTER)
+}
@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// scanner shows the results of a scan and lets the
+// user add responding addresses as datapoints.
+type scanner struct {
+	*table.Model
+
+	KeyMap ScanKeyMap
+	Help   help.Model
+	Status *Status
+
+	rng    *ScanRange
+	cancel context.CancelFunc
+
+	// hits are the responding addresses and
+	// addresses which failed with other errors
+	// than an illegal data address exception.
+	hits     []ScanResult
+	selected map[uint16]bool
+
+	scanned int
+	illegal int
+	done    bool
+	added   int
+}
+
+func newScanner(theme *huh.Theme, rng *ScanRange, cancel context.CancelFunc) *scanner {
+	t := newStyledTable()
+	t.SetColumns([]table.Column{
+		{Title: " ", Width: 1},
+		{Title: "Address", Width: 7},
+		{Title: "Value", Width: 16},
+		{Title: "Error", Width: 40},
+	})
+	t.Focus()
+
+	sc := &scanner{
+		Model:    &t,
+		KeyMap:   DefaultScanKeyMap(t.KeyMap),
+		Help:     help.New(),
+		Status:   NewStatus(theme),
+		rng:      rng,
+		cancel:   cancel,
+		selected: map[uint16]bool{},
+	}
+	sc.Help.ShowAll = true
+
+	return sc
+}
+
+// add adds the result of a scanned address.
+func (s *scanner) add(res ScanResult) {
+	s.scanned++
+	if res.IsIllegalAddress() {
+		s.illegal++
+		return
+	}
+
+	s.hits = append(s.hits, res)
+	s.updateRows()
+}
+
+// selectedDatapoints returns new datapoints for the selected
+// addresses, or for the address at the cursor if none is selected.
+func (s *scanner) selectedDatapoints() []*Datapoint {
+	var dps []*Datapoint
+	for _, hit := range s.hits {
+		if s.selected[hit.Addr] && hit.Err == nil {
+			dps = append(dps, hit.Datapoint())
+		}
+	}
+
+	if len(dps) == 0 && s.Cursor() < len(s.hits) {
+		if hit := s.hits[s.Cursor()]; hit.Err == nil {
+			dps = append(dps, hit.Datapoint())
+		}
+	}
+
+	return dps
+}
+
+func (s *scanner) updateRows() {
+	rows := make([]table.Row, len(s.hits))
+	for i, hit := range s.hits {
+		mark := " "
+		if s.selected[hit.Addr] {
+			mark = "●"
+		}
+
+		var err string
+		if hit.Err != nil {
+			err = hit.Err.Error()
+		}
+		rows[i] = table.Row{mark, fmt.Sprintf("%d", hit.Addr), hit.FormattedValue(), err}
+	}
+	s.SetRows(rows)
+}
+
+// update handles key messages. It returns the datapoints
+// which should be added to the table, and false if the
+// user wants to leave the scanner.
+func (s *scanner) update(msg tea.KeyMsg) ([]*Datapoint, bool, tea.Cmd) {
+	switch {
+	case key.Matches(msg, s.KeyMap.Quit):
+		s.cancel()
+		return nil, false, tea.ClearScreen
+
+	case key.Matches(msg, s.KeyMap.Select):
+		if s.Cursor() < len(s.hits) {
+			addr := s.hits[s.Cursor()].Addr
+			s.selected[addr] = !s.selected[addr]
+			s.updateRows()
+		}
+		return nil, true, nil
+
+	case key.Matches(msg, s.KeyMap.Add):
+		dps := s.selectedDatapoints()
+		s.added += len(dps)
+		s.selected = map[uint16]bool{}
+		s.updateRows()
+		return dps, true, nil
+	}
+
+	table, cmd := s.Model.Update(msg)
+	s.Model = &table
+
+	return nil, true, cmd
+}
+
+func (s *scanner) View() string {
+	total := int(s.rng.End) - int(s.rng.Start) + 1
+	progress := fmt.Sprintf("Scanning %s %d–%d of server %d: %d/%d", s.rng.Space, s.rng.Start, s.rng.End, s.rng.SlaveId, s.scanned, total)
+	if s.done {
+		progress = fmt.Sprintf("Scanned %s %d–%d of server %d", s.rng.Space, s.rng.Start, s.rng.End, s.rng.SlaveId)
+	}
+	s.Status.Text = fmt.Sprintf("%s, %d responding, %d illegal, %d added", progress, s.scanned-s.illegal-s.failed(), s.illegal, s.added)
+
+	return baseStyle.Render(s.Model.View()) + "\n" + s.Status.View(s.Model.Width()) + "\n" + s.Help.View(s.KeyMap) + "\n"
+}
+
+// failed returns the number of addresses which failed
+// with other errors than illegal data address exceptions.
+func (s *scanner) failed() int {
+	n := 0
+	for _, hit := range s.hits {
+		if hit.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+type ScanKeyMap struct {
+	Table  table.KeyMap
+	Select key.Binding
+	Add    key.Binding
+	Quit   key.Binding
+}
+
+func DefaultScanKeyMap(km table.KeyMap) ScanKeyMap {
+	return ScanKeyMap{
+		Table: km,
+		Select: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "select"),
+		),
+		Add: key.NewBinding(
+			key.WithKeys("+"),
+			key.WithHelp("+", "add as datapoints"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc", "back"),
+		),
+	}
+}
+
+// ShortHelp implements the KeyMap interface.
+func (km ScanKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{km.Table.LineUp, km.Table.LineDown, km.Select, km.Add, km.Quit}
+}
+
+// FullHelp implements the KeyMap interface.
+func (km ScanKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{km.Table.LineUp, km.Table.LineDown}, {km.Select, km.Add, km.Quit}}
+}
+
+// promptScan lets the user specify the range to scan.
+func promptScan(rng ScanRange) (ScanRange, error) {
+	theme := Theme
+	theme.Focused.Title = theme.Focused.Title.Width(15).AlignHorizontal(lipgloss.Right)
+	theme.Blurred.Title = theme.Blurred.Title.Foreground(theme.Blurred.TextInput.Text.GetForeground()).Width(15).AlignHorizontal(lipgloss.Right)
+
+	km := huh.NewDefaultKeyMap()
+	km.Quit.SetKeys("esc")
+
+	scan := true
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Server ID").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&rng.SlaveId)).
+				WithTheme(theme),
+
+			huh.NewSelect[Space]().
+				Title("Space").
+				Inline(true).
+				Options(
+					huh.NewOption("Holding Register", SpaceHoldingRegister),
+					huh.NewOption("Input Register", SpaceInputRegister),
+					huh.NewOption("Coil", SpaceCoil),
+					huh.NewOption("Discrete Input", SpaceDiscreteInput),
+				).
+				Value(&rng.Space).
+				WithTheme(theme),
+
+			newIntInput(0, 65_535).
+				Title("Start Address").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&rng.Start)).
+				WithTheme(theme),
+
+			newIntInput(0, 65_535).
+				Title("End Address").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&rng.End)).
+				WithTheme(theme),
+
+			huh.NewConfirm().
+				Affirmative("Scan").
+				Negative("Cancel").
+				Value(&scan).
+				WithTheme(theme),
+		),
+	).
+		WithKeyMap(km).
+		Run()
+
+	if !scan || err != nil {
+		return rng, errors.New("canceled")
+	}
+
+	if rng.End < rng.Start {
+		return rng, errors.New("end address before start address")
+	}
+
+	return rng, nil
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/simonvetter/modbus"
+)
+
+// detectTimeout is the timeout of a request while detecting
+// the serial settings of a unit.
+const detectTimeout = 300 * time.Millisecond
+
+// serialPortPatterns match the paths of serial devices.
+var serialPortPatterns = []string{
+	"/dev/ttyUSB*",
+	"/dev/ttyACM*",
+	"/dev/serial/by-id/*",
+	"/dev/cu.usbserial*",
+	"/dev/cu.usbmodem*",
+}
+
+// detectBaudRates are the baud rates which are tried
+// when detecting serial settings, the most common first.
+var detectBaudRates = []uint{19_200, 9600, 38_400, 115_200, 57_600, 4800, 2400, 1200}
+
+// detectFramings are the combinations of parity and stop bits
+// which are tried when detecting serial settings.
+var detectFramings = []struct {
+	Parity   uint
+	StopBits uint
+}{
+	{modbus.PARITY_EVEN, 1},
+	{modbus.PARITY_NONE, 2},
+	{modbus.PARITY_NONE, 1},
+	{modbus.PARITY_ODD, 1},
+}
+
+// SerialPorts returns the paths of available serial devices.
+func SerialPorts() []string {
+	var ports []string
+	for _, pattern := range serialPortPatterns {
+		matches, _ := filepath.Glob(pattern)
+		ports = append(ports, matches...)
+	}
+
+	return ports
+}
+
+// SerialSettings returns the serial settings, e.g. "19200 8E1".
+func (c ModbusConfiguration) SerialSettings() string {
+	parity := "E"
+	switch c.Parity {
+	case modbus.PARITY_NONE:
+		parity = "N"
+	case modbus.PARITY_ODD:
+		parity = "O"
+	}
+
+	return fmt.Sprintf("%d %d%s%d", c.BaudRate, c.DataBits, parity, c.StopBits)
+}
+
+// DetectSerial tries common baud rates, parities and stop bits until
+// the unit responds to a read of holding register 0. Exceptions are
+// valid responses too. fn is called before every attempt. The detected
+// settings are stored in cfg.
+func DetectSerial(cfg *ModbusConfiguration, unitId uint8, fn func(ModbusConfiguration)) error {
+	for _, baudRate := range detectBaudRates {
+		for _, framing := range detectFramings {
+			c := *cfg
+			c.BaudRate = baudRate
+			c.DataBits = 8
+			c.Parity = framing.Parity
+			c.StopBits = framing.StopBits
+			c.Timeout = Duration(detectTimeout)
+			fn(c)
+
+			client, err := modbus.NewClient(c.ClientConfiguration())
+			if err != nil {
+				return err
+			}
+
+			if err := client.Open(); err != nil {
+				return err
+			}
+
+			client.SetUnitId(unitId)
+			_, err = client.ReadRegisters(0, 1, modbus.HOLDING_REGISTER)
+			client.Close()
+
+			if err == nil || IsException(err) {
+				cfg.BaudRate = c.BaudRate
+				cfg.DataBits = c.DataBits
+				cfg.Parity = c.Parity
+				cfg.StopBits = c.StopBits
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("server %d did not respond with any serial settings", unitId)
+}
@@ -0,0 +1,424 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/simonvetter/modbus"
+	"github.com/xiam/to"
+)
+
+// generatorTick is the resolution of generator intervals.
+const generatorTick = 100 * time.Millisecond
+
+// SimulatorChangedMsg is sent when values of the simulator changed,
+// because a client wrote values or generators produced new values.
+type SimulatorChangedMsg struct{}
+
+// registerKey identifies a register or bit of a slave.
+type registerKey struct {
+	SlaveId uint8
+	Space   Space
+	Addr    uint16
+}
+
+// Simulator is a modbus server which serves the values of datapoints.
+// Every slave id has its own register map, which only contains the
+// registers of the datapoints of the slave. Requests to other
+// registers fail with an illegal data address exception.
+type Simulator struct {
+	mu      sync.Mutex
+	regs    map[registerKey]uint16
+	slaves  map[uint8]bool
+	changed chan struct{}
+
+	// gens are the states of the generators of datapoints.
+	gens map[*Datapoint]*generatorState
+
+	stop func() error
+	done chan struct{}
+}
+
+// NewSimulator returns a simulator which serves the datapoints.
+// The generators of the datapoints run until the simulator is stopped.
+// An error is returned if a generator can not be started.
+func NewSimulator(datapoints []*Datapoint) (*Simulator, error) {
+	s := &Simulator{
+		regs:    map[registerKey]uint16{},
+		slaves:  map[uint8]bool{},
+		changed: make(chan struct{}, 1),
+		gens:    map[*Datapoint]*generatorState{},
+		done:    make(chan struct{}),
+	}
+	err := s.SetDatapoints(datapoints)
+	go s.runGenerators()
+
+	return s, err
+}
+
+// SetDatapoints maps the registers of the datapoints. Values
+// of registers which were mapped before are kept. An error is
+// returned if a generator can not be started.
+func (s *Simulator) SetDatapoints(datapoints []*Datapoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	regs := map[registerKey]uint16{}
+	slaves := map[uint8]bool{}
+	for _, dp := range datapoints {
+		slaves[dp.SlaveId] = true
+		for i := uint16(0); i < quantity(dp); i++ {
+			key := registerKey{dp.SlaveId, dp.Space, dp.Addr + i}
+			regs[key] = s.regs[key]
+		}
+	}
+
+	var errs []error
+	gens := map[*Datapoint]*generatorState{}
+	for _, dp := range datapoints {
+		if dp.Generator == nil || dp.Generator.Kind == GeneratorNone {
+			continue
+		}
+
+		if st, ok := s.gens[dp]; ok {
+			gens[dp] = st
+			continue
+		}
+
+		st, err := dp.Generator.start(time.Now())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dp.Name, err))
+			continue
+		}
+		gens[dp] = st
+	}
+
+	s.regs = regs
+	s.slaves = slaves
+	s.gens = gens
+
+	return errors.Join(errs...)
+}
+
+// Serve starts serving at the url, which is either
+// tcp://host:port or rtuovertcp://host:port.
+func (s *Simulator) Serve(url string) error {
+	switch {
+	case strings.HasPrefix(url, "tcp://"):
+		server, err := modbus.NewServer(&modbus.ServerConfiguration{URL: url}, s)
+		if err != nil {
+			return err
+		}
+
+		if err := server.Start(); err != nil {
+			return err
+		}
+		s.stop = server.Stop
+
+	case strings.HasPrefix(url, "rtuovertcp://"):
+		server, err := newRTUOverTCPServer(strings.TrimPrefix(url, "rtuovertcp://"), s)
+		if err != nil {
+			return err
+		}
+		s.stop = server.Stop
+
+	default:
+		return fmt.Errorf(`unsupported url "%s"`, url)
+	}
+
+	return nil
+}
+
+// Stop stops serving and the generators.
+func (s *Simulator) Stop() error {
+	close(s.done)
+
+	if s.stop == nil {
+		return nil
+	}
+	return s.stop()
+}
+
+// Listen returns a command which waits until values changed.
+// The command has to be returned again after every SimulatorChangedMsg.
+func (s *Simulator) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-s.changed:
+			return SimulatorChangedMsg{}
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+// runGenerators sets the values of datapoints
+// with generators until the simulator is stopped.
+func (s *Simulator) runGenerators() {
+	ticker := time.NewTicker(generatorTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if s.generate(now) {
+				s.notify()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// generate sets the values of datapoints whose generator interval
+// elapsed. It returns true if any value was set.
+func (s *Simulator) generate(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for dp, st := range s.gens {
+		if now.Before(st.next) {
+			continue
+		}
+		st.next = now.Add(dp.Generator.interval())
+
+		val := dp.codec().generatedValue(dp.Generator.next(st, now))
+		changed = true
+
+		// Bit-fields share the register with other datapoints
+		if dp.DataType == DataTypeBits && !dp.Space.IsBit() {
+			key := registerKey{dp.SlaveId, dp.Space, dp.Addr}
+			s.regs[key] = dp.codec().mergeBits(s.regs[key], val)
+			continue
+		}
+
+		for i, v := range datapointRegisters(dp, val) {
+			s.regs[registerKey{dp.SlaveId, dp.Space, dp.Addr + uint16(i)}] = v
+		}
+	}
+
+	return changed
+}
+
+// notify notifies listeners about changed values.
+func (s *Simulator) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// read returns the values of the registers.
+func (s *Simulator) read(slave uint8, space Space, addr uint16, quantity uint16) ([]uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.slaves[slave] {
+		return nil, modbus.ErrGWTargetFailedToRespond
+	}
+
+	values := make([]uint16, quantity)
+	for i := range values {
+		v, ok := s.regs[registerKey{slave, space, addr + uint16(i)}]
+		if !ok {
+			return nil, modbus.ErrIllegalDataAddress
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// write sets the values of the registers.
+func (s *Simulator) write(slave uint8, space Space, addr uint16, values []uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.slaves[slave] {
+		return modbus.ErrGWTargetFailedToRespond
+	}
+
+	for i := range values {
+		if _, ok := s.regs[registerKey{slave, space, addr + uint16(i)}]; !ok {
+			return modbus.ErrIllegalDataAddress
+		}
+	}
+
+	for i, v := range values {
+		s.regs[registerKey{slave, space, addr + uint16(i)}] = v
+	}
+
+	return nil
+}
+
+// writeBits sets the bits of a bit-field datapoint.
+func (s *Simulator) writeBits(dp *Datapoint, val any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.slaves[dp.SlaveId] {
+		return modbus.ErrGWTargetFailedToRespond
+	}
+
+	key := registerKey{dp.SlaveId, dp.Space, dp.Addr}
+	reg, ok := s.regs[key]
+	if !ok {
+		return modbus.ErrIllegalDataAddress
+	}
+	s.regs[key] = dp.codec().mergeBits(reg, val)
+
+	return nil
+}
+
+// clientWrite sets the values of the registers
+// and notifies listeners about the write.
+func (s *Simulator) clientWrite(slave uint8, space Space, addr uint16, values []uint16) error {
+	if err := s.write(slave, space, addr, values); err != nil {
+		return err
+	}
+	s.notify()
+
+	return nil
+}
+
+// HandleCoils implements the modbus.RequestHandler interface.
+func (s *Simulator) HandleCoils(req *modbus.CoilsRequest) ([]bool, error) {
+	if req.IsWrite {
+		return nil, s.clientWrite(req.UnitId, SpaceCoil, req.Addr, boolsToUint16s(req.Args))
+	}
+
+	values, err := s.read(req.UnitId, SpaceCoil, req.Addr, req.Quantity)
+	return uint16sToBools(values), err
+}
+
+// HandleDiscreteInputs implements the modbus.RequestHandler interface.
+func (s *Simulator) HandleDiscreteInputs(req *modbus.DiscreteInputsRequest) ([]bool, error) {
+	values, err := s.read(req.UnitId, SpaceDiscreteInput, req.Addr, req.Quantity)
+	return uint16sToBools(values), err
+}
+
+// HandleHoldingRegisters implements the modbus.RequestHandler interface.
+func (s *Simulator) HandleHoldingRegisters(req *modbus.HoldingRegistersRequest) ([]uint16, error) {
+	if req.IsWrite {
+		return nil, s.clientWrite(req.UnitId, SpaceHoldingRegister, req.Addr, req.Args)
+	}
+
+	return s.read(req.UnitId, SpaceHoldingRegister, req.Addr, req.Quantity)
+}
+
+// HandleInputRegisters implements the modbus.RequestHandler interface.
+func (s *Simulator) HandleInputRegisters(req *modbus.InputRegistersRequest) ([]uint16, error) {
+	return s.read(req.UnitId, SpaceInputRegister, req.Addr, req.Quantity)
+}
+
+// datapointRegisters returns the registers or bits
+// which store the value of the datapoint.
+func datapointRegisters(dp *Datapoint, val any) []uint16 {
+	if dp.Space.IsBit() {
+		return boolsToUint16s([]bool{to.Bool(val)})
+	}
+	return dp.codec().encodeValue(val)
+}
+
+func boolsToUint16s(bools []bool) []uint16 {
+	values := make([]uint16, len(bools))
+	for i, b := range bools {
+		if b {
+			values[i] = 1
+		}
+	}
+	return values
+}
+
+func uint16sToBools(values []uint16) []bool {
+	bools := make([]bool, len(values))
+	for i, v := range values {
+		bools[i] = v != 0
+	}
+	return bools
+}
+
+// simulatorClient reads and writes the registers of a simulator.
+// In contrast to modbus clients, it can also set the values of
+// input registers and discrete inputs.
+type simulatorClient struct {
+	sim    *Simulator
+	unitId uint8
+}
+
+func (c *simulatorClient) SetUnitId(id uint8) error {
+	c.unitId = id
+	return nil
+}
+
+func (c *simulatorClient) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	values, err := c.sim.read(c.unitId, SpaceCoil, addr, quantity)
+	return uint16sToBools(values), err
+}
+
+func (c *simulatorClient) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	values, err := c.sim.read(c.unitId, SpaceDiscreteInput, addr, quantity)
+	return uint16sToBools(values), err
+}
+
+func (c *simulatorClient) ReadRegisters(addr uint16, quantity uint16, regType modbus.RegType) ([]uint16, error) {
+	space := SpaceHoldingRegister
+	if regType == modbus.INPUT_REGISTER {
+		space = SpaceInputRegister
+	}
+	return c.sim.read(c.unitId, space, addr, quantity)
+}
+
+func (c *simulatorClient) WriteCoil(addr uint16, value bool) error {
+	return c.sim.write(c.unitId, SpaceCoil, addr, boolsToUint16s([]bool{value}))
+}
+
+func (c *simulatorClient) WriteRegister(addr uint16, value uint16) error {
+	return c.sim.write(c.unitId, SpaceHoldingRegister, addr, []uint16{value})
+}
+
+func (c *simulatorClient) WriteRegisters(addr uint16, values []uint16) error {
+	return c.sim.write(c.unitId, SpaceHoldingRegister, addr, values)
+}
+
+// ReportServerId returns the unit id as server id
+// and the run indicator status on.
+func (c *simulatorClient) ReportServerId(unitId uint8) ([]byte, error) {
+	c.sim.mu.Lock()
+	defer c.sim.mu.Unlock()
+
+	if !c.sim.slaves[unitId] {
+		return nil, modbus.ErrGWTargetFailedToRespond
+	}
+
+	return []byte{unitId, 0xff}, nil
+}
+
+// writeDatapoint sets the value of the datapoint in any register space.
+func (c *simulatorClient) writeDatapoint(dp *Datapoint, val any) error {
+	if dp.DataType == DataTypeBits && !dp.Space.IsBit() {
+		return c.sim.writeBits(dp, val)
+	}
+	return c.sim.write(dp.SlaveId, dp.Space, dp.Addr, datapointRegisters(dp, val))
+}
+
+// PromptSimulator shows the table of datapoints, whose values
+// are served by the simulator at the url. Values can't be
+// written in the table if readOnly is true.
+func PromptSimulator(sim *Simulator, url string, datapoints []*Datapoint, readOnly bool) ([]*Datapoint, error) {
+	t := NewTable(Theme, newPoller(&simulatorClient{sim: sim}))
+	t.sim = sim
+	t.SetReadOnly(readOnly)
+	t.Status.Link = LinkServing
+	t.Status.Text = url
+	t.SetDatapoints(datapoints)
+
+	err := runTable(t)
+
+	return t.Datapoints, err
+}
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -10,16 +12,38 @@ type Status struct {
 	Err        error
 	AutoReload bool
 
+	// Interval describes the auto reload interval.
+	Interval string
+
+	// Link is the state of the modbus connection.
+	Link LinkState
+
+	// LinkAttempt is the number of the current reconnect attempt.
+	LinkAttempt int
+
+	// LinkConnection is the name of the connection whose link state
+	// is shown, or empty for the default connection.
+	LinkConnection string
+
+	// ReadOnly is true if values can't be written.
+	ReadOnly bool
+
 	textStyle       lipgloss.Style
 	errStyle        lipgloss.Style
 	autoReloadStyle lipgloss.Style
+	connectedStyle  lipgloss.Style
+	reconnectStyle  lipgloss.Style
+	readOnlyStyle   lipgloss.Style
 }
 
 func NewStatus(theme *huh.Theme) *Status {
 	return &Status{
 		textStyle:       theme.Focused.Base,
 		errStyle:        theme.Focused.ErrorMessage,
-		autoReloadStyle: lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("#58F236")).SetString("Auto Reloading"),
+		autoReloadStyle: lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("#58F236")),
+		connectedStyle:  lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("240")),
+		reconnectStyle:  lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("#F2C036")),
+		readOnlyStyle:   lipgloss.NewStyle().Padding(0, 1).Bold(true).Foreground(lipgloss.Color("#F25D36")),
 	}
 }
 
@@ -35,6 +59,8 @@ func (s Status) View(width int) string {
 	var err string
 	var empty string
 	var autoReload string
+	var link string
+	var readOnly string
 
 	if s.Text != "" {
 		text = s.textStyle.Render(s.Text)
@@ -45,11 +71,28 @@ func (s Status) View(width int) string {
 	}
 
 	if s.AutoReload {
-		autoReload = s.autoReloadStyle.Render()
+		autoReload = s.autoReloadStyle.Render(fmt.Sprintf("Auto Reloading (%s)", s.Interval))
+	}
+
+	if s.ReadOnly {
+		readOnly = s.readOnlyStyle.Render("Read-only")
+	}
+
+	switch s.Link {
+	case LinkConnected:
+		link = s.connectedStyle.Render("Connected")
+	case LinkReconnecting:
+		if s.LinkConnection != "" {
+			link = s.reconnectStyle.Render(fmt.Sprintf("Reconnecting %s (%d)…", s.LinkConnection, s.LinkAttempt))
+		} else {
+			link = s.reconnectStyle.Render(fmt.Sprintf("Reconnecting (%d)…", s.LinkAttempt))
+		}
+	case LinkServing:
+		link = s.autoReloadStyle.Render("Serving")
 	}
 
-	emptySpace := width - w(text, err, autoReload)
+	emptySpace := width - w(text, err, readOnly, autoReload, link)
 	empty = lipgloss.NewStyle().Width(emptySpace).Render()
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, text, err, empty, autoReload)
+	return lipgloss.JoinHorizontal(lipgloss.Top, text, err, empty, readOnly, autoReload, link)
 }
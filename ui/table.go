@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,25 +12,56 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/simonvetter/modbus"
-	"github.com/xiam/to"
 )
 
-func PromptTable(client *modbus.ModbusClient, datapoints []*Datapoint) ([]*Datapoint, error) {
-	t := NewTable(Theme, client)
+// TableOptions are options of the table.
+type TableOptions struct {
+	// ReadOnly disables writing values.
+	ReadOnly bool
+
+	// History logs written values, if not nil.
+	History *History
+}
+
+// PromptTable shows the datapoints in a table. The datapoints of the
+// default connection are read with client, the datapoints of the
+// other connections with their own client.
+func PromptTable(client *modbus.ModbusClient, cfg *ModbusConfiguration, datapoints []*Datapoint, connections []*Connection, opts TableOptions) ([]*Datapoint, []*Connection, error) {
+	t := NewTable(Theme, NewPoller(client, cfg.Retries))
+	t.SetReadOnly(opts.ReadOnly)
+	t.SetHistory(opts.History)
+	t.poller.conf = cfg.ClientConfiguration()
+	t.MaxGap = cfg.MaxGap
+	if cfg.PollInterval > 0 {
+		t.Interval = time.Duration(cfg.PollInterval)
+	}
+	for _, c := range connections {
+		if err := t.AddConnection(c); err != nil {
+			t.Status.Err = err
+		}
+	}
 	t.SetDatapoints(datapoints)
 
-	// Read all datapoints on launch
-	t.refreshAllDatapoints()
+	err := runTable(t)
+
+	// Keep the selected auto reload interval
+	cfg.PollInterval = Duration(t.Interval)
 
+	// Return the new list of datapoints and connections
+	return t.Datapoints, t.Connections, err
+}
+
+// runTable runs the table until the user quits.
+func runTable(t *Model) error {
 	// Focus the table
 	t.Focus()
 
 	// Run the program
 	p := tea.NewProgram(t)
 	_, err := p.Run()
+	t.poller.Stop()
 
-	// Return the new list of datapoints
-	return t.Datapoints, err
+	return err
 }
 
 var baseStyle = lipgloss.NewStyle().
@@ -44,15 +76,63 @@ type Model struct {
 	Status         *Status
 	MaxColumnWidth int
 
+	// MaxGap is the maximum number of unused registers between
+	// datapoints which are read with a single request.
+	MaxGap uint16
+
+	// Interval is the auto reload interval of datapoints
+	// which don't specify their own poll interval.
+	Interval time.Duration
+
 	Datapoints []*Datapoint
 	LastEdited *Datapoint
 
+	// ReadOnly is true if values can't be written.
+	ReadOnly bool
+
+	// History logs written values, if not nil.
+	History *History
+
+	// Connections are the named connections besides
+	// the default connection.
+	Connections []*Connection
+
 	needsLayout bool
 
-	modbus *modbus.ModbusClient
+	// tickSeq identifies the current auto reload ticks.
+	tickSeq int
+
+	poller *Poller
+
+	// links are the most recent link states by connection name.
+	links map[string]LinkStateMsg
+
+	// sim is the simulator which serves the datapoints, if any.
+	sim *Simulator
+
+	// scanner is shown instead of the table while scanning.
+	scanner *scanner
+
+	// lastScan is the most recently scanned range.
+	lastScan ScanRange
+
+	// unitScanner is shown instead of the table while probing units.
+	unitScanner *unitScanner
+
+	// history is shown instead of the table while browsing
+	// the written values.
+	history *historyView
+
+	// lastProbe is the most recently used unit probe.
+	lastProbe UnitProbe
+
+	// unitId is the server id of new datapoints,
+	// which the user selected in the unit scanner.
+	unitId *uint8
 }
 
-func NewTable(theme *huh.Theme, client *modbus.ModbusClient) *Model {
+// newStyledTable returns a table with the styles of the user interface.
+func newStyledTable() table.Model {
 	t := table.New()
 	s := table.DefaultStyles()
 	s.Header = s.Header.
@@ -67,10 +147,18 @@ func NewTable(theme *huh.Theme, client *modbus.ModbusClient) *Model {
 		Bold(false)
 
 	t.SetStyles(s)
+
+	return t
+}
+
+func NewTable(theme *huh.Theme, poller *Poller) *Model {
+	t := newStyledTable()
 	t.SetColumns([]table.Column{
 		{Title: "#"},
+		{Title: "Connection"},
 		{Title: "Server ID"},
 		{Title: "Address"},
+		{Title: "Space"},
 		{Title: "Flags"},
 		{Title: "Name"},
 		{Title: "Description"},
@@ -83,7 +171,11 @@ func NewTable(theme *huh.Theme, client *modbus.ModbusClient) *Model {
 		Help:           help.New(),
 		Status:         NewStatus(theme),
 		MaxColumnWidth: 50,
-		modbus:         client,
+		Interval:       1 * time.Second,
+		poller:         poller,
+		links:          map[string]LinkStateMsg{},
+		lastScan:       ScanRange{SlaveId: 1, End: 99},
+		lastProbe:      DefaultUnitProbe(),
 	}
 	m.Help.ShowAll = true
 
@@ -97,87 +189,219 @@ func (m *Model) SetDatapoints(datapoints []*Datapoint) {
 	m.needsLayout = true
 }
 
-// newDatapoint returns a new datapoint with the slave id
-// set based on existing datapoints.
+// SetReadOnly enables or disables writing values.
+func (m *Model) SetReadOnly(readOnly bool) {
+	m.ReadOnly = readOnly
+	m.Status.ReadOnly = readOnly
+	m.updateWriteKeys()
+}
+
+// updateWriteKeys enables the write key bindings
+// if values can be written to the selected datapoint.
+func (m *Model) updateWriteKeys() {
+	dp := m.SelectedDatapoint()
+	enabled := !m.ReadOnly && dp != nil
+	if enabled && m.sim == nil {
+		enabled = WriteError(dp) == nil
+	}
+
+	m.KeyMap.Write.SetEnabled(enabled)
+	m.KeyMap.Toggle.SetEnabled(enabled)
+	m.KeyMap.Pulse.SetEnabled(enabled)
+}
+
+// writableDatapoint returns the selected datapoint,
+// or nil if values can't be written to it.
+func (m *Model) writableDatapoint() *Datapoint {
+	dp := m.SelectedDatapoint()
+	if dp == nil || m.ReadOnly {
+		return nil
+	}
+
+	// The simulator sets the values of all datapoints
+	if m.sim != nil {
+		return dp
+	}
+
+	if err := WriteError(dp); err != nil {
+		m.Status.Err = err
+		return nil
+	}
+
+	return dp
+}
+
+// pulseEndMsg is sent when a pulsed datapoint is switched off.
+type pulseEndMsg struct {
+	Datapoint *Datapoint
+}
+
+// endPulse returns a command which switches a pulsed datapoint
+// off after the pulse duration, if switching it on succeeded.
+func (m *Model) endPulse(msg WriteResultMsg) tea.Cmd {
+	if msg.Pulse == 0 {
+		return nil
+	}
+
+	if msg.Err != nil {
+		m.Status.Err = fmt.Errorf("pulse failed: %w", msg.Err)
+		return nil
+	}
+
+	dp := msg.Datapoint
+	return tea.Tick(msg.Pulse, func(time.Time) tea.Msg {
+		return pulseEndMsg{Datapoint: dp}
+	})
+}
+
+// SetHistory sets the history which logs written values.
+// The history can be browsed if it's not nil.
+func (m *Model) SetHistory(h *History) {
+	m.History = h
+	m.KeyMap.History.SetEnabled(h != nil)
+}
+
+// AddConnection adds a named connection, whose datapoints are
+// read concurrently to the datapoints of other connections.
+// The connection is added even if its client can't be created,
+// so that it's not lost.
+func (m *Model) AddConnection(c *Connection) error {
+	m.Connections = append(m.Connections, c)
+
+	conf := c.Modbus.ClientConfiguration()
+	client, err := modbus.NewClient(conf)
+	if err != nil {
+		return fmt.Errorf(`connection "%s": %w`, c.Name, err)
+	}
+	m.poller.Attach(c.Name, client, conf, c.Modbus.Retries)
+
+	return nil
+}
+
+// connectionNames returns the names of the named connections.
+func (m *Model) connectionNames() []string {
+	names := make([]string, len(m.Connections))
+	for i, c := range m.Connections {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// newDatapoint returns a new datapoint with the connection
+// and slave id set based on existing datapoints.
 func (m *Model) newDatapoint() Datapoint {
 	new := Datapoint{}
 	if m.LastEdited != nil {
+		new.Connection = m.LastEdited.Connection
 		new.SlaveId = m.LastEdited.SlaveId
 	} else {
 		for _, dp := range m.Datapoints {
+			new.Connection = dp.Connection
 			new.SlaveId = dp.SlaveId
 			break
 		}
 	}
 
+	// The unit picked in the unit scanner
+	// replaces the slave id only
+	if m.unitId != nil {
+		new.SlaveId = *m.unitId
+	}
+
 	return new
 }
 
-func (m *Model) refreshAllDatapoints() {
-	for _, dp := range m.Datapoints {
-		m.readDatapoint(dp)
-	}
-}
-func (m *Model) readDatapoint(dp *Datapoint) {
-	m.modbus.SetUnitId(dp.SlaveId)
-
-	var val any
-	var err error
-	switch dp.DataType {
-	case DataTypeCoil:
-		val, err = m.modbus.ReadCoil(dp.Addr)
-	case DataTypeBool:
-		val, err = m.modbus.ReadDiscreteInput(dp.Addr)
-	case DataTypeUint16:
-		val, err = m.modbus.ReadRegister(dp.Addr, dp.RegType())
-	case DataTypeUint32:
-		val, err = m.modbus.ReadUint32(dp.Addr, dp.RegType())
-	case DataTypeUint64:
-		val, err = m.modbus.ReadUint64(dp.Addr, dp.RegType())
-	case DataTypeFloat32:
-		val, err = m.modbus.ReadFloat32(dp.Addr, dp.RegType())
-	case DataTypeFloat64:
-		val, err = m.modbus.ReadFloat64(dp.Addr, dp.RegType())
+// updateLink shows the link state of the default connection,
+// or of a named connection which is reconnecting.
+func (m *Model) updateLink() {
+	link := m.links[""]
+	for _, c := range m.Connections {
+		if l, ok := m.links[c.Name]; ok && l.State == LinkReconnecting && link.State != LinkReconnecting {
+			link = l
+		}
 	}
 
-	if err != nil {
-		dp.Err = err
-		dp.Value = nil
-	} else {
-		dp.Err = nil
-		dp.Value = val
+	m.Status.Link = link.State
+	m.Status.LinkAttempt = link.Attempt
+	m.Status.LinkConnection = link.Connection
+
+	// Other errors, e.g. of writes, are kept
+	if link.Err != nil {
+		m.Status.Err = link.Err
 	}
 }
 
-func (m *Model) writeDatapointValue(dp *Datapoint, val any) {
-	m.modbus.SetUnitId(dp.SlaveId)
+// refreshAllDatapoints returns a command which reads all
+// datapoints which are not already being read.
+func (m *Model) refreshAllDatapoints() tea.Cmd {
+	return m.readDatapoints(startReading(m.Datapoints, func(*Datapoint) bool {
+		return true
+	}))
+}
+
+// refreshDueDatapoints returns a command which reads the
+// datapoints whose poll interval has elapsed.
+func (m *Model) refreshDueDatapoints() tea.Cmd {
+	return m.readDatapoints(dueDatapoints(m.Datapoints, m.Interval))
+}
 
-	var err error
-	switch dp.DataType {
-	case DataTypeBool:
-		err = m.modbus.WriteCoil(dp.Addr, to.Bool(val))
-	case DataTypeUint16:
-		err = m.modbus.WriteRegister(dp.Addr, uint16(to.Uint64(val)))
-	case DataTypeUint32:
-		err = m.modbus.WriteUint32(dp.Addr, uint32(to.Uint64(val)))
-	case DataTypeUint64:
-		err = m.modbus.WriteUint64(dp.Addr, to.Uint64(val))
-	case DataTypeFloat32:
-		err = m.modbus.WriteFloat32(dp.Addr, float32(to.Float64(val)))
-	case DataTypeFloat64:
-		err = m.modbus.WriteFloat64(dp.Addr, to.Float64(val))
+func (m *Model) readDatapoints(datapoints []*Datapoint) tea.Cmd {
+	if len(datapoints) == 0 {
+		return nil
 	}
 
-	if err != nil {
-		dp.Err = err
-		dp.Value = nil
-	} else {
-		dp.Err = nil
-		dp.Value = val
+	return m.poller.Read(planReads(datapoints, m.MaxGap))
+}
+
+// tickInterval returns the shortest poll interval of all datapoints.
+func (m *Model) tickInterval() time.Duration {
+	return tickInterval(m.Datapoints, m.Interval)
+}
+
+// intervalDescription returns the range of poll intervals.
+func (m *Model) intervalDescription() string {
+	fastest, slowest := m.Interval, m.Interval
+	for _, dp := range m.Datapoints {
+		fastest = min(fastest, pollInterval(dp, m.Interval))
+		slowest = max(slowest, pollInterval(dp, m.Interval))
+	}
+
+	if fastest == slowest {
+		return fastest.String()
 	}
+	return fmt.Sprintf("%s–%s", fastest, slowest)
 }
 
-func (m Model) Init() tea.Cmd { return nil }
+// startAutoReload returns a command which starts auto reloading.
+// Ticks of previous auto reloads are ignored.
+func (m *Model) startAutoReload() tea.Cmd {
+	m.Status.AutoReload = true
+	m.tickSeq++
+	return refreshTickMsg(m.tickInterval(), m.tickSeq)
+}
+
+// stopAutoReload stops auto reloading.
+func (m *Model) stopAutoReload() {
+	m.Status.AutoReload = false
+	m.tickSeq++
+}
+
+// datapointsChanged updates the register map of the simulator
+// after datapoints were added, edited or removed.
+func (m *Model) datapointsChanged() {
+	if m.sim != nil {
+		m.Status.Err = m.sim.SetDatapoints(m.Datapoints)
+	}
+}
+
+// Init reads all datapoints on launch.
+func (m *Model) Init() tea.Cmd {
+	cmds := []tea.Cmd{m.poller.Listen(), m.refreshAllDatapoints()}
+	if m.sim != nil {
+		cmds = append(cmds, m.sim.Listen())
+	}
+	return tea.Batch(cmds...)
+}
 
 func (m Model) SelectedDatapoint() *Datapoint {
 	selectedIndex := m.Cursor()
@@ -195,43 +419,225 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		return m, tea.ClearScreen
 	case RefreshTickMsg:
-		m.refreshAllDatapoints()
+		if !m.Status.AutoReload || msg.seq != m.tickSeq {
+			return m, nil
+		}
+
+		refresh := m.refreshDueDatapoints()
 		m.updateRows()
 
-		if !m.Status.AutoReload {
-			return m, tea.ClearScreen
+		return m, tea.Batch(refresh, refreshTickMsg(m.tickInterval(), m.tickSeq))
+
+	case ReadResultMsg:
+		msg.apply()
+		m.updateRows()
+		return m, m.poller.Listen()
+
+	case LinkStateMsg:
+		// The error of the link is cleared when it recovers
+		if prev := m.links[msg.Connection]; prev.Err != nil && m.Status.Err == prev.Err {
+			m.Status.Err = nil
 		}
+		m.links[msg.Connection] = msg
+		m.updateLink()
+		return m, m.poller.Listen()
 
-		return m, tea.Sequence(tea.ClearScreen, refreshTickMsg(1*time.Second))
+	case WriteResultMsg:
+		dp := msg.Datapoint
+		dp.Written = nil
+		switch {
+		case msg.Err != nil:
+			dp.Err = msg.Err
+			dp.Value = nil
+		case msg.ReadBackErr != nil:
+			dp.Err = fmt.Errorf("reading back failed: %w", msg.ReadBackErr)
+			dp.Value = nil
+		default:
+			dp.Err = nil
+			dp.Value = msg.ReadBack
+			if msg.Mismatch() {
+				dp.Written = msg.Value
+			}
+		}
+
+		if m.History != nil {
+			if err := m.History.Append(msg.HistoryEntry()); err != nil {
+				m.Status.Err = err
+			}
+		}
+
+		m.updateRows()
+		return m, tea.Batch(m.poller.Listen(), m.endPulse(msg))
+
+	case pulseEndMsg:
+		return m, m.poller.Write(msg.Datapoint, false)
+
+	case ScanResultMsg:
+		if m.scanner != nil && m.scanner.rng == msg.Range {
+			m.scanner.add(msg.Result)
+		}
+		return m, m.poller.Listen()
+
+	case ScanDoneMsg:
+		if m.scanner != nil && m.scanner.rng == msg.Range {
+			m.scanner.done = true
+		}
+		return m, m.poller.Listen()
+
+	case UnitResultMsg:
+		if m.unitScanner != nil && m.unitScanner.probe == msg.Probe {
+			m.unitScanner.add(msg.Result)
+		}
+		return m, m.poller.Listen()
+
+	case UnitScanDoneMsg:
+		if m.unitScanner != nil && m.unitScanner.probe == msg.Probe {
+			m.unitScanner.done = true
+			m.unitScanner.err = msg.Err
+		}
+		return m, m.poller.Listen()
+
+	case SimulatorChangedMsg:
+		refresh := m.refreshAllDatapoints()
+		m.updateRows()
+		return m, tea.Batch(refresh, m.sim.Listen())
 
 	case tea.KeyMsg:
+		if m.scanner != nil {
+			return m.updateScanner(msg)
+		}
+
+		if m.unitScanner != nil {
+			return m.updateUnitScanner(msg)
+		}
+
+		if m.history != nil {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+
+			ok, cmd := m.history.update(msg)
+			if !ok {
+				m.history = nil
+			}
+			return m, cmd
+		}
+
+		m.updateWriteKeys()
 		switch {
+		case key.Matches(msg, m.KeyMap.ScanUnits):
+			probe, err := promptUnitProbe(m.lastProbe)
+			if err != nil {
+				return m, tea.ClearScreen
+			}
+			m.lastProbe = probe
+
+			ctx, cancel := context.WithCancel(context.Background())
+			m.unitScanner = newUnitScanner(Theme, &probe, cancel)
+			return m, tea.Batch(tea.ClearScreen, m.poller.ProbeUnits(ctx, &probe))
+
+		case key.Matches(msg, m.KeyMap.Scan):
+			rng, err := promptScan(m.lastScan)
+			if err != nil {
+				return m, tea.ClearScreen
+			}
+			m.lastScan = rng
+
+			ctx, cancel := context.WithCancel(context.Background())
+			m.scanner = newScanner(Theme, &rng, cancel)
+			return m, tea.Batch(tea.ClearScreen, m.poller.Scan(ctx, &rng))
+
+		case key.Matches(msg, m.KeyMap.AddConnection):
+			c, err := promptConnection(m.Connections)
+			if err != nil {
+				return m, tea.ClearScreen
+			}
+
+			if err := m.AddConnection(c); err != nil {
+				m.Status.Err = err
+			}
+			return m, tea.ClearScreen
+
 		case key.Matches(msg, m.KeyMap.Write):
-			dp := m.SelectedDatapoint()
+			dp := m.writableDatapoint()
 			if dp == nil {
 				break
 			}
 
 			val, err := promptWrite(*dp)
 			if err == nil {
-				m.writeDatapointValue(dp, val)
-				m.updateRows()
+				return m, tea.Batch(tea.ClearScreen, m.poller.Write(dp, val))
 			}
 
 			return m, tea.ClearScreen
 
+		case key.Matches(msg, m.KeyMap.Toggle):
+			dp := m.writableDatapoint()
+			if dp == nil || !dp.IsSwitch() {
+				break
+			}
+
+			val, err := dp.toggledValue()
+			if err != nil {
+				m.Status.Err = err
+				break
+			}
+
+			if err := promptConfirmWrite(*dp, val); err != nil {
+				return m, tea.ClearScreen
+			}
+
+			return m, m.poller.Write(dp, val)
+
+		case key.Matches(msg, m.KeyMap.Pulse):
+			dp := m.writableDatapoint()
+			if dp == nil || !dp.IsSwitch() {
+				break
+			}
+
+			if err := promptConfirmWrite(*dp, true); err != nil {
+				return m, tea.ClearScreen
+			}
+
+			return m, m.poller.Pulse(dp, dp.pulseDuration())
+
+		case key.Matches(msg, m.KeyMap.History):
+			if m.History == nil {
+				break
+			}
+
+			entries, err := m.History.Entries()
+			if err != nil {
+				m.Status.Err = err
+				break
+			}
+
+			m.history = newHistoryView(Theme, entries)
+			return m, tea.ClearScreen
+
 		case key.Matches(msg, m.KeyMap.Refresh):
-			m.refreshAllDatapoints()
+			refresh := m.refreshAllDatapoints()
 			m.updateRows()
-			return m, tea.ClearScreen
+			return m, refresh
 
 		case key.Matches(msg, m.KeyMap.StopRefresh):
-			m.Status.AutoReload = false
+			m.stopAutoReload()
 			return m, tea.ClearScreen
 
 		case key.Matches(msg, m.KeyMap.RefreshEverySec):
-			m.Status.AutoReload = true
-			return m, tea.Sequence(tea.ClearScreen, refreshTickMsg(1*time.Second))
+			return m, tea.Sequence(tea.ClearScreen, m.startAutoReload())
+
+		case key.Matches(msg, m.KeyMap.Interval):
+			interval, err := promptInterval(m.Interval)
+			if err != nil {
+				return m, tea.ClearScreen
+			}
+
+			m.Interval = interval
+			if m.Status.AutoReload {
+				return m, tea.Sequence(tea.ClearScreen, m.startAutoReload())
+			}
+			return m, tea.ClearScreen
 
 		case key.Matches(msg, m.KeyMap.Edit):
 			dp := m.SelectedDatapoint()
@@ -239,21 +645,24 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 
-			edited, err := promptDatapoint(*dp, fmt.Sprintf(`Edit "%s"`, dp.Name))
+			edited, err := promptDatapoint(*dp, fmt.Sprintf(`Edit "%s"`, dp.Name), m.connectionNames())
 			if err == nil {
+				edited.Reading = false
 
 				m.Datapoints[m.Cursor()] = &edited
 				m.LastEdited = &edited
 
+				m.datapointsChanged()
 				m.updateRows()
 			}
 			return m, tea.ClearScreen
 		case key.Matches(msg, m.KeyMap.Add):
-			new, err := promptDatapoint(m.newDatapoint(), "New Datapoint")
+			new, err := promptDatapoint(m.newDatapoint(), "New Datapoint", m.connectionNames())
 			if err == nil {
 
 				m.Datapoints = append(m.Datapoints, &new)
 
+				m.datapointsChanged()
 				m.updateRows()
 			}
 			return m, tea.ClearScreen
@@ -262,12 +671,14 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if dp == nil {
 				break
 			}
-			updated, err := promptDatapoint(*dp, "New Datapoint")
+			updated, err := promptDatapoint(*dp, "New Datapoint", m.connectionNames())
 			if err == nil {
 				updated.Value = nil
+				updated.Reading = false
 
 				m.Datapoints = append(m.Datapoints, &updated)
 
+				m.datapointsChanged()
 				m.updateRows()
 			}
 			return m, tea.ClearScreen
@@ -279,6 +690,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			i := m.Cursor()
 			m.Datapoints = append(m.Datapoints[:i], m.Datapoints[i+1:]...)
+			m.datapointsChanged()
 			m.updateRows()
 
 			if i < len(m.Rows()) {
@@ -328,13 +740,69 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateScanner handles key messages while scanning.
+func (m *Model) updateScanner(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		m.scanner.cancel()
+		return m, tea.Quit
+	}
+
+	dps, ok, cmd := m.scanner.update(msg)
+	if len(dps) > 0 {
+		m.Datapoints = append(m.Datapoints, dps...)
+		m.datapointsChanged()
+		m.updateRows()
+		m.needsLayout = true
+	}
+
+	if !ok {
+		m.scanner = nil
+	}
+
+	return m, cmd
+}
+
+// updateUnitScanner handles key messages while probing units.
+func (m *Model) updateUnitScanner(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		m.unitScanner.cancel()
+		return m, tea.Quit
+	}
+
+	id, ok, cmd := m.unitScanner.update(msg)
+	if id != nil {
+		m.unitId = id
+	}
+
+	if !ok {
+		m.unitScanner = nil
+	}
+
+	return m, cmd
+}
+
 func (m Model) View() string {
+	if m.scanner != nil {
+		return m.scanner.View()
+	}
+
+	if m.unitScanner != nil {
+		return m.unitScanner.View()
+	}
+
+	if m.history != nil {
+		return m.history.View()
+	}
+
 	if m.needsLayout {
 		m.layoutSubviews()
 		m.Model.UpdateViewport()
 	}
 
+	m.updateWriteKeys()
 	m.KeyMap.AutoReload = m.Status.AutoReload
+	m.KeyMap.RefreshEverySec.SetHelp("l", fmt.Sprintf("auto reload (%s)", m.Interval))
+	m.Status.Interval = m.intervalDescription()
 	return baseStyle.Render(m.Model.View()) + "\n" + m.Status.View(m.Model.Width()) + "\n" + m.HelpView() + "\n"
 }
 
@@ -374,7 +842,14 @@ type KeyMap struct {
 	Refresh         key.Binding
 	RefreshEverySec key.Binding
 	StopRefresh     key.Binding
+	Interval        key.Binding
 	Write           key.Binding
+	Toggle          key.Binding
+	Pulse           key.Binding
+	Scan            key.Binding
+	ScanUnits       key.Binding
+	AddConnection   key.Binding
+	History         key.Binding
 
 	Duplicate    key.Binding
 	MoveLineUp   key.Binding
@@ -402,6 +877,14 @@ func DefaultKeyMap(km table.KeyMap) KeyMap {
 			key.WithKeys("w"),
 			key.WithHelp("w", "write"),
 		),
+		Toggle: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "toggle"),
+		),
+		Pulse: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pulse"),
+		),
 		Refresh: key.NewBinding(
 			key.WithKeys("r"),
 			key.WithHelp("r", "reload"),
@@ -414,6 +897,27 @@ func DefaultKeyMap(km table.KeyMap) KeyMap {
 			key.WithKeys("ctrl+l"),
 			key.WithHelp("ctrl+l", "stop auto reload"),
 		),
+		Interval: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "reload interval"),
+		),
+		Scan: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "scan"),
+		),
+		ScanUnits: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "find server ids"),
+		),
+		AddConnection: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "add connection"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", "write history"),
+			key.WithDisabled(),
+		),
 		Add: key.NewBinding(
 			key.WithKeys("+"),
 			key.WithHelp("+", "add"),
@@ -449,8 +953,8 @@ func (km KeyMap) ShortHelp() []key.Binding {
 // FullHelp implements the KeyMap interface.
 func (km KeyMap) FullHelp() [][]key.Binding {
 	upDown := []key.Binding{km.Table.LineUp, km.Table.LineDown, km.MoveLineUp, km.MoveLineDown}
-	editing := []key.Binding{km.Add, km.Remove, km.Edit, km.Write, km.Duplicate}
-	refresh := []key.Binding{km.Refresh, km.RefreshEverySec}
+	editing := []key.Binding{km.Add, km.Remove, km.Edit, km.Write, km.Toggle, km.Pulse, km.Duplicate, km.Scan, km.ScanUnits, km.AddConnection}
+	refresh := []key.Binding{km.Refresh, km.RefreshEverySec, km.Interval, km.History}
 	if km.AutoReload {
 		refresh[1] = km.StopRefresh
 	}
@@ -0,0 +1,400 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/simonvetter/modbus"
+)
+
+// defaultProbeTimeout is the timeout of probe requests
+// if the probe doesn't specify it.
+const defaultProbeTimeout = 200 * time.Millisecond
+
+// UnitProbe represents the request which is sent to
+// unit ids to find out which units are present on a bus.
+// The request reads a single register or bit, or reports
+// the server id.
+type UnitProbe struct {
+	First uint8
+	Last  uint8
+	Space Space
+	Addr  uint16
+
+	// ReportServerId is true if the probe sends Report Server
+	// ID requests instead of reading the register at Addr.
+	ReportServerId bool
+
+	// Timeout is the timeout of a probe request.
+	Timeout Duration
+
+	// Retries is the number of times a failed probe request is
+	// retried. Requests to missing units are not retried.
+	Retries uint
+}
+
+// DefaultUnitProbe returns a probe which reads
+// holding register 0 of the unit ids 1–247.
+func DefaultUnitProbe() UnitProbe {
+	return UnitProbe{
+		First:   1,
+		Last:    247,
+		Space:   SpaceHoldingRegister,
+		Timeout: Duration(defaultProbeTimeout),
+	}
+}
+
+func (p UnitProbe) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return time.Duration(p.Timeout)
+	}
+	return defaultProbeTimeout
+}
+
+// sender returns the function which sends the probe request
+// with client to a unit id.
+func (p UnitProbe) sender(client Client) func(id uint8) ([]byte, error) {
+	if p.ReportServerId {
+		if r, ok := client.(serverIdReporter); ok {
+			return r.ReportServerId
+		}
+
+		return func(uint8) ([]byte, error) {
+			return nil, errReportNotSupported
+		}
+	}
+
+	return func(id uint8) ([]byte, error) {
+		_, err := readRaw(client, id, p.Space, p.Addr, 1)
+		return nil, err
+	}
+}
+
+// UnitResult is the result of probing a unit id.
+type UnitResult struct {
+	SlaveId uint8
+	Err     error
+
+	// ServerId is the response to a Report Server ID request.
+	ServerId []byte
+}
+
+// Response returns a description of the response.
+func (r UnitResult) Response() string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case r.ServerId != nil:
+		return fmt.Sprintf("server id % x", r.ServerId)
+	}
+	return "ok"
+}
+
+// Responds returns true if the unit responded to the probe.
+// Units which return an exception are present too.
+func (r UnitResult) Responds() bool {
+	return r.Err == nil || IsException(r.Err) && !r.Missing()
+}
+
+// Missing returns true if no unit with the id responded,
+// because the request timed out or a gateway couldn't
+// reach the unit.
+func (r UnitResult) Missing() bool {
+	switch {
+	case errors.Is(r.Err, modbus.ErrRequestTimedOut),
+		errors.Is(r.Err, modbus.ErrGWPathUnavailable),
+		errors.Is(r.Err, modbus.ErrGWTargetFailedToRespond):
+		return true
+	}
+	return false
+}
+
+// UnitResultMsg is sent when a unit id was probed.
+type UnitResultMsg struct {
+	Probe  *UnitProbe
+	Result UnitResult
+}
+
+// UnitScanDoneMsg is sent when probing finished or was canceled.
+type UnitScanDoneMsg struct {
+	Probe *UnitProbe
+
+	// Err is not-nil if probing could not be started.
+	Err error
+}
+
+// ProbeUnits opens a client for the configuration, whose requests
+// time out after the timeout of the probe, sends the probe to every
+// unit id and calls fn with the result. Probing stops when ctx is
+// canceled.
+func ProbeUnits(ctx context.Context, conf modbus.ClientConfiguration, probe UnitProbe, fn func(UnitResult)) error {
+	conf.Timeout = probe.timeout()
+
+	if probe.ReportServerId {
+		c, err := dialServerIdClient(conf)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		probeUnits(ctx, probe, c.ReportServerId, fn)
+		return nil
+	}
+
+	client, err := modbus.NewClient(&conf)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	probeUnits(ctx, probe, probe.sender(client), fn)
+	return nil
+}
+
+// probeUnits sends the probe with send to every unit id and calls fn
+// with the result. Probing stops when ctx is canceled.
+func probeUnits(ctx context.Context, probe UnitProbe, send func(id uint8) ([]byte, error), fn func(UnitResult)) {
+	for id := int(probe.First); id <= int(probe.Last); id++ {
+		res := UnitResult{SlaveId: uint8(id)}
+		for attempt := uint(0); attempt <= probe.Retries; attempt++ {
+			if ctx.Err() != nil {
+				return
+			}
+
+			res.ServerId, res.Err = send(uint8(id))
+			if res.Err == nil || IsException(res.Err) || res.Missing() {
+				break
+			}
+		}
+		fn(res)
+	}
+}
+
+// unitScanner shows the units which responded to a probe and
+// lets the user select the server id of new datapoints.
+type unitScanner struct {
+	*table.Model
+
+	KeyMap UnitScanKeyMap
+	Help   help.Model
+	Status *Status
+
+	probe  *UnitProbe
+	cancel context.CancelFunc
+
+	// units are the responding units and units which
+	// failed with other errors, e.g. invalid responses.
+	units   []UnitResult
+	probed  int
+	done    bool
+	err     error
+	missing int
+}
+
+func newUnitScanner(theme *huh.Theme, probe *UnitProbe, cancel context.CancelFunc) *unitScanner {
+	t := newStyledTable()
+	t.SetColumns([]table.Column{
+		{Title: "Server ID", Width: 9},
+		{Title: "Response", Width: 40},
+	})
+	t.Focus()
+
+	s := &unitScanner{
+		Model:  &t,
+		KeyMap: DefaultUnitScanKeyMap(t.KeyMap),
+		Help:   help.New(),
+		Status: NewStatus(theme),
+		probe:  probe,
+		cancel: cancel,
+	}
+	s.Help.ShowAll = true
+
+	return s
+}
+
+// add adds the result of a probed unit id.
+func (s *unitScanner) add(res UnitResult) {
+	s.probed++
+	if res.Missing() {
+		s.missing++
+		return
+	}
+
+	s.units = append(s.units, res)
+
+	rows := make([]table.Row, len(s.units))
+	for i, u := range s.units {
+		rows[i] = table.Row{fmt.Sprintf("%d", u.SlaveId), u.Response()}
+	}
+	s.SetRows(rows)
+}
+
+// update handles key messages. It returns the selected unit
+// id, and false if the user wants to leave the unit scanner.
+func (s *unitScanner) update(msg tea.KeyMsg) (*uint8, bool, tea.Cmd) {
+	switch {
+	case key.Matches(msg, s.KeyMap.Quit):
+		s.cancel()
+		return nil, false, tea.ClearScreen
+
+	case key.Matches(msg, s.KeyMap.Select):
+		if s.Cursor() >= len(s.units) {
+			return nil, true, nil
+		}
+
+		s.cancel()
+		id := s.units[s.Cursor()].SlaveId
+		return &id, false, tea.ClearScreen
+	}
+
+	table, cmd := s.Model.Update(msg)
+	s.Model = &table
+
+	return nil, true, cmd
+}
+
+func (s *unitScanner) View() string {
+	total := int(s.probe.Last) - int(s.probe.First) + 1
+	switch {
+	case s.err != nil:
+		s.Status.Text = ""
+		s.Status.Err = s.err
+	case s.done:
+		s.Status.Text = fmt.Sprintf("Probed server ids %d–%d, %d found, %d not responding", s.probe.First, s.probe.Last, len(s.units), s.missing)
+	default:
+		s.Status.Text = fmt.Sprintf("Probing server ids %d–%d: %d/%d, %d found", s.probe.First, s.probe.Last, s.probed, total, len(s.units))
+	}
+
+	return baseStyle.Render(s.Model.View()) + "\n" + s.Status.View(s.Model.Width()) + "\n" + s.Help.View(s.KeyMap) + "\n"
+}
+
+type UnitScanKeyMap struct {
+	Table  table.KeyMap
+	Select key.Binding
+	Quit   key.Binding
+}
+
+func DefaultUnitScanKeyMap(km table.KeyMap) UnitScanKeyMap {
+	return UnitScanKeyMap{
+		Table: km,
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "use for new datapoints"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc", "back"),
+		),
+	}
+}
+
+// ShortHelp implements the KeyMap interface.
+func (km UnitScanKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{km.Table.LineUp, km.Table.LineDown, km.Select, km.Quit}
+}
+
+// FullHelp implements the KeyMap interface.
+func (km UnitScanKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{km.Table.LineUp, km.Table.LineDown}, {km.Select, km.Quit}}
+}
+
+// promptUnitProbe lets the user specify the unit ids and the probe request.
+func promptUnitProbe(probe UnitProbe) (UnitProbe, error) {
+	theme := Theme
+	theme.Focused.Title = theme.Focused.Title.Width(15).AlignHorizontal(lipgloss.Right)
+	theme.Blurred.Title = theme.Blurred.Title.Foreground(theme.Blurred.TextInput.Text.GetForeground()).Width(15).AlignHorizontal(lipgloss.Right)
+
+	km := huh.NewDefaultKeyMap()
+	km.Quit.SetKeys("esc")
+
+	scan := true
+	err := huh.NewForm(
+		huh.NewGroup(
+			newIntInput(1, 247).
+				Title("First Server ID").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&probe.First)).
+				WithTheme(theme),
+
+			newIntInput(1, 247).
+				Title("Last Server ID").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&probe.Last)).
+				WithTheme(theme),
+
+			huh.NewSelect[bool]().
+				Title("Probe Request").
+				Inline(true).
+				Options(
+					huh.NewOption("Read", false),
+					huh.NewOption("Report Server ID", true),
+				).
+				Value(&probe.ReportServerId).
+				WithTheme(theme),
+		),
+		huh.NewGroup(
+			huh.NewSelect[Space]().
+				Title("Probe Space").
+				Inline(true).
+				Options(
+					huh.NewOption("Holding Register", SpaceHoldingRegister),
+					huh.NewOption("Input Register", SpaceInputRegister),
+					huh.NewOption("Coil", SpaceCoil),
+					huh.NewOption("Discrete Input", SpaceDiscreteInput),
+				).
+				Value(&probe.Space).
+				WithTheme(theme),
+
+			newIntInput(0, 65_535).
+				Title("Probe Address").
+				Prompt(":").
+				Inline(true).
+				Accessor(NewNumberAccessor(&probe.Addr)).
+				WithTheme(theme),
+		).WithHideFunc(func() bool {
+			return probe.ReportServerId
+		}),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Timeout").
+				Prompt(":").
+				Placeholder(defaultProbeTimeout.String()).
+				Validate(validateDuration).
+				Inline(true).
+				Accessor(NewDurationAccessor(&probe.Timeout)).
+				WithTheme(theme),
+
+			huh.NewConfirm().
+				Affirmative("Scan").
+				Negative("Cancel").
+				Value(&scan).
+				WithTheme(theme),
+		),
+	).
+		WithKeyMap(km).
+		Run()
+
+	if !scan || err != nil {
+		return probe, errors.New("canceled")
+	}
+
+	if probe.Last < probe.First {
+		return probe, errors.New("last server id before first server id")
+	}
+
+	return probe, nil
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Watch reads the datapoints with the poller until ctx is done.
+// Like auto reloading in the table, datapoints are read every
+// interval or with their own poll interval. The results are
+// applied to the datapoints and passed to fn; fn is also called
+// with every LinkStateMsg.
+func Watch(ctx context.Context, p *Poller, datapoints []*Datapoint, interval time.Duration, maxGap uint16, fn func(msg tea.Msg)) {
+	results := make(chan tea.Msg)
+	go func() {
+		for {
+			msg := p.Listen()()
+			if msg == nil {
+				return
+			}
+
+			select {
+			case results <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	tick := time.NewTimer(0)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-tick.C:
+			if due := dueDatapoints(datapoints, interval); len(due) > 0 {
+				go p.Read(planReads(due, maxGap))()
+			}
+			tick.Reset(tickInterval(datapoints, interval))
+
+		case msg := <-results:
+			if msg, ok := msg.(ReadResultMsg); ok {
+				msg.apply()
+			}
+			fn(msg)
+		}
+	}
+}
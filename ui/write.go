@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xiam/to"
+)
+
+// defaultPulseDuration is how long datapoints are switched
+// on when pulsed if they don't specify it.
+const defaultPulseDuration = time.Second
+
+// ErrReadOnlySpace is returned when writing
+// to input registers or discrete inputs.
+var ErrReadOnlySpace = errors.New("input registers and discrete inputs are read-only")
+
+// ErrReadOnlyDatapoint is returned when writing
+// to datapoints with the read flag.
+var ErrReadOnlyDatapoint = errors.New("datapoint is read-only")
+
+// datapointWriter is implemented by clients which
+// write datapoints in a different way than modbus clients.
+type datapointWriter interface {
+	writeDatapoint(dp *Datapoint, val any) error
+}
+
+// WriteDatapoint writes the value to the datapoint. Coils are
+// written with Write Single Coil, also if their datatype is the
+// deprecated coil datatype. Writing to input registers and discrete
+// inputs fails with ErrReadOnlySpace, and writing to datapoints with
+// the read flag fails with ErrReadOnlyDatapoint.
+func WriteDatapoint(client Client, dp *Datapoint, val any) error {
+	if w, ok := client.(datapointWriter); ok {
+		return w.writeDatapoint(dp, val)
+	}
+
+	if err := WriteError(dp); err != nil {
+		return err
+	}
+
+	client.SetUnitId(dp.SlaveId)
+
+	switch {
+	case dp.Space == SpaceCoil:
+		return client.WriteCoil(dp.Addr, to.Bool(val))
+	case dp.DataType == DataTypeBits:
+		return writeBits(client, dp, val)
+	case dp.codec().registerCount() == 1:
+		return client.WriteRegister(dp.Addr, dp.codec().encodeValue(val)[0])
+	}
+
+	return client.WriteRegisters(dp.Addr, dp.codec().encodeValue(val))
+}
+
+// WriteError returns the error why values
+// can't be written to the datapoint, or nil.
+func WriteError(dp *Datapoint) error {
+	switch {
+	case !dp.Space.IsWritable():
+		return ErrReadOnlySpace
+	case dp.Flag == FlagRead:
+		return ErrReadOnlyDatapoint
+	}
+
+	return nil
+}
+
+// IsSwitch returns true if the value of the datapoint is
+// a bool, which can be toggled and pulsed.
+func (dp Datapoint) IsSwitch() bool {
+	switch {
+	case dp.Space.IsBit(), dp.DataType == DataTypeBool, dp.DataType == DataTypeCoil:
+		return true
+	case dp.DataType == DataTypeBits:
+		return dp.Mask.single()
+	}
+	return false
+}
+
+// toggledValue returns the inverted value of a switch. The
+// value is unknown until the datapoint was read successfully.
+func (dp Datapoint) toggledValue() (any, error) {
+	if dp.Value == nil {
+		return nil, errors.New("value unknown; reload before toggling")
+	}
+	return !to.Bool(dp.Value), nil
+}
+
+// pulseDuration returns how long the datapoint
+// is switched on when it's pulsed.
+func (dp Datapoint) pulseDuration() time.Duration {
+	if dp.PulseDuration > 0 {
+		return time.Duration(dp.PulseDuration)
+	}
+	return defaultPulseDuration
+}
+
+// writeBits writes the bits of a bit-field by reading the register,
+// changing the bits and writing the register. Mask Write Register
+// (function code 22) is not supported by the modbus library. Bits
+// which are changed between the read and the write are overwritten.
+func writeBits(client Client, dp *Datapoint, val any) error {
+	regs, err := client.ReadRegisters(dp.Addr, 1, dp.Space.regType())
+	if err != nil {
+		return err
+	}
+
+	return client.WriteRegister(dp.Addr, dp.codec().mergeBits(regs[0], val))
+}
+
+// VerifyDatapoint reads the datapoint and returns an
+// error if the read value differs from val.
+func VerifyDatapoint(client Client, dp *Datapoint, val any) error {
+	ReadDatapoints(client, []*Datapoint{dp}, 0)
+	if dp.Err != nil {
+		return dp.Err
+	}
+
+	if !dp.codec().equalValues(dp.Value, val) {
+		return fmt.Errorf("read back value %v differs from written value %v", dp.Value, val)
+	}
+
+	return nil
+}
+
+// readBack returns the value of the datapoint
+// without changing the datapoint.
+func readBack(client Client, dp *Datapoint) (val any, err error) {
+	readBlocks(client, planReads([]*Datapoint{dp}, 0), func(_ *Datapoint, v any, e error) {
+		val, err = v, e
+	})
+	return val, err
+}
+
+// equalValues returns true if both values are stored
+// as the same registers or bits.
+func (c codec) equalValues(a, b any) bool {
+	if c.Space.IsBit() {
+		return to.Bool(a) == to.Bool(b)
+	}
+	return slices.Equal(c.encodeValue(a), c.encodeValue(b))
+}
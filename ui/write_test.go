@@ -0,0 +1,319 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/simonvetter/modbus"
+	"github.com/xiam/to"
+)
+
+// fakeClient stores registers and bits in memory
+// and records the requests it receives.
+type fakeClient struct {
+	unitId uint8
+	regs   map[uint16]uint16
+	bits   map[uint16]bool
+
+	// requests are the sent requests, e.g. "WriteRegister 1".
+	requests []string
+
+	// err is returned by write requests, if not nil.
+	err error
+
+	// unmapped are the addresses which fail to be read
+	// with an illegal data address exception.
+	unmapped map[uint16]bool
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{regs: map[uint16]uint16{}, bits: map[uint16]bool{}, unmapped: map[uint16]bool{}}
+}
+
+func (c *fakeClient) record(format string, args ...any) {
+	c.requests = append(c.requests, fmt.Sprintf(format, args...))
+}
+
+func (c *fakeClient) SetUnitId(id uint8) error {
+	c.unitId = id
+	return nil
+}
+
+func (c *fakeClient) ReadCoils(addr uint16, quantity uint16) ([]bool, error) {
+	c.record("ReadCoils %d", addr)
+	return c.readBits(addr, quantity)
+}
+
+func (c *fakeClient) ReadDiscreteInputs(addr uint16, quantity uint16) ([]bool, error) {
+	c.record("ReadDiscreteInputs %d", addr)
+	return c.readBits(addr, quantity)
+}
+
+func (c *fakeClient) readBits(addr uint16, quantity uint16) ([]bool, error) {
+	if err := c.checkMapped(addr, quantity); err != nil {
+		return nil, err
+	}
+
+	values := make([]bool, quantity)
+	for i := range values {
+		values[i] = c.bits[addr+uint16(i)]
+	}
+	return values, nil
+}
+
+// checkMapped returns an exception if any of the addresses is unmapped.
+func (c *fakeClient) checkMapped(addr uint16, quantity uint16) error {
+	for i := uint16(0); i < quantity; i++ {
+		if c.unmapped[addr+i] {
+			return modbus.ErrIllegalDataAddress
+		}
+	}
+	return nil
+}
+
+func (c *fakeClient) ReadRegisters(addr uint16, quantity uint16, regType modbus.RegType) ([]uint16, error) {
+	c.record("ReadRegisters %d", addr)
+	if err := c.checkMapped(addr, quantity); err != nil {
+		return nil, err
+	}
+
+	values := make([]uint16, quantity)
+	for i := range values {
+		values[i] = c.regs[addr+uint16(i)]
+	}
+	return values, nil
+}
+
+func (c *fakeClient) WriteCoil(addr uint16, value bool) error {
+	c.record("WriteCoil %d", addr)
+	if c.err != nil {
+		return c.err
+	}
+	c.bits[addr] = value
+	return nil
+}
+
+func (c *fakeClient) WriteRegister(addr uint16, value uint16) error {
+	c.record("WriteRegister %d", addr)
+	if c.err != nil {
+		return c.err
+	}
+	c.regs[addr] = value
+	return nil
+}
+
+func (c *fakeClient) WriteRegisters(addr uint16, values []uint16) error {
+	c.record("WriteRegisters %d", addr)
+	if c.err != nil {
+		return c.err
+	}
+	for i, v := range values {
+		c.regs[addr+uint16(i)] = v
+	}
+	return nil
+}
+
+func TestWriteBitsByteOrder(t *testing.T) {
+	orders := []ByteOrder{ByteOrderABCD, ByteOrderDCBA, ByteOrderBADC, ByteOrderCDAB}
+	for _, order := range orders {
+		t.Run(order.String(), func(t *testing.T) {
+			dp := &Datapoint{
+				SlaveId:   1,
+				Addr:      10,
+				Space:     SpaceHoldingRegister,
+				DataType:  DataTypeBits,
+				ByteOrder: order,
+				Mask:      0x0f00,
+			}
+
+			client := newFakeClient()
+			client.regs[10] = 0x1234
+			if err := writeBits(client, dp, uint16(5)); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := dp.codec().decodeRegisters([]uint16{client.regs[10]}); v != uint16(5) {
+				t.Fatalf("%v != 5", v)
+			}
+
+			// The other bits of the register are not changed
+			want := order.reorder([]uint16{0x1234})[0]&^0x0f00 | 0x0500
+			if reg := order.reorder([]uint16{client.regs[10]})[0]; reg != want {
+				t.Fatalf("%#04x != %#04x", reg, want)
+			}
+
+			if client.requests[len(client.requests)-1] != "WriteRegister 10" {
+				t.Fatalf("unexpected requests %v", client.requests)
+			}
+		})
+	}
+}
+
+func TestWriteDatapoint(t *testing.T) {
+	// values are written values and the requests
+	// which write them to a holding register
+	tests := []struct {
+		dataType DataType
+		value    any
+		requests []string
+	}{
+		{DataTypeUint16, uint16(7), []string{"WriteRegister 10"}},
+		{DataTypeInt16, int16(-3), []string{"WriteRegister 10"}},
+		{DataTypeBool, true, []string{"WriteRegister 10"}},
+		{DataTypeCoil, true, []string{"WriteRegister 10"}},
+		{DataTypeUint32, uint32(70_000), []string{"WriteRegisters 10"}},
+		{DataTypeInt32, int32(-70_000), []string{"WriteRegisters 10"}},
+		{DataTypeUint64, uint64(1 << 40), []string{"WriteRegisters 10"}},
+		{DataTypeInt64, int64(-1 << 40), []string{"WriteRegisters 10"}},
+		{DataTypeFloat32, float32(1.5), []string{"WriteRegisters 10"}},
+		{DataTypeFloat64, 2.25, []string{"WriteRegisters 10"}},
+		{DataTypeString, "abcd", []string{"WriteRegisters 10"}},
+		{DataTypeBits, true, []string{"ReadRegisters 10", "WriteRegister 10"}},
+	}
+
+	for _, space := range []Space{SpaceCoil, SpaceDiscreteInput, SpaceHoldingRegister, SpaceInputRegister} {
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%s %s", space, test.dataType), func(t *testing.T) {
+				dp := &Datapoint{SlaveId: 3, Addr: 10, Space: space, Flag: FlagReadWrite, DataType: test.dataType, Length: 2, Mask: 0x0010}
+				client := newFakeClient()
+				err := WriteDatapoint(client, dp, test.value)
+
+				switch space {
+				case SpaceDiscreteInput, SpaceInputRegister:
+					if err != ErrReadOnlySpace || len(client.requests) > 0 {
+						t.Fatalf("%v, %v", err, client.requests)
+					}
+					return
+				case SpaceCoil:
+					if err != nil || !slices.Equal(client.requests, []string{"WriteCoil 10"}) || client.bits[10] != to.Bool(test.value) {
+						t.Fatalf("%v, %v", err, client.requests)
+					}
+				default:
+					if err != nil || !slices.Equal(client.requests, test.requests) {
+						t.Fatalf("%v, %v", err, client.requests)
+					}
+
+					regs := []uint16{client.regs[10], client.regs[11], client.regs[12], client.regs[13]}
+					if v := dp.codec().decodeRegisters(regs[:dp.codec().registerCount()]); v != test.value {
+						t.Fatalf("%v != %v", v, test.value)
+					}
+				}
+
+				if client.unitId != 3 {
+					t.Fatalf("unit id %d", client.unitId)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteReadOnlyDatapoint(t *testing.T) {
+	dp := &Datapoint{SlaveId: 1, Addr: 10, Space: SpaceHoldingRegister, Flag: FlagRead, DataType: DataTypeUint16}
+	client := newFakeClient()
+	if err := WriteDatapoint(client, dp, uint16(7)); err != ErrReadOnlyDatapoint || len(client.requests) > 0 {
+		t.Fatalf("%v, %v", err, client.requests)
+	}
+}
+
+func TestWriteMigratedCoil(t *testing.T) {
+	// Coils were stored with the deprecated coil datatype
+	var dp Datapoint
+	if err := json.Unmarshal([]byte(`{"slaveId":1,"addr":4,"data-type":6}`), &dp); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newFakeClient()
+	if err := WriteDatapoint(client, &dp, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(client.requests, []string{"WriteCoil 4"}) || !client.bits[4] {
+		t.Fatalf("unexpected requests %v", client.requests)
+	}
+}
+
+func TestToggledValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  any
+	}{
+		{true, false},
+		{false, true},
+		{uint16(1), false},
+		{uint16(0), true},
+	}
+
+	for _, test := range tests {
+		dp := Datapoint{Space: SpaceCoil, DataType: DataTypeBool, Value: test.value}
+		if v, err := dp.toggledValue(); err != nil || v != test.want {
+			t.Fatalf("%v: %v, %v", test.value, v, err)
+		}
+	}
+
+	// The value of datapoints which weren't read is unknown
+	dp := Datapoint{Space: SpaceCoil, DataType: DataTypeBool}
+	if _, err := dp.toggledValue(); err == nil {
+		t.Fatal("toggled unknown value")
+	}
+}
+
+func TestPulse(t *testing.T) {
+	client := newFakeClient()
+	p := newPoller(client)
+	defer p.Stop()
+
+	m := NewTable(Theme, p)
+	dp := &Datapoint{SlaveId: 1, Addr: 4, Space: SpaceCoil, Flag: FlagReadWrite, DataType: DataTypeBool}
+
+	// The datapoint is switched off after the pulse
+	// duration, which starts when it was switched on
+	p.Pulse(dp, time.Millisecond)()
+	on := p.Listen()().(WriteResultMsg)
+	if on.Err != nil || on.Value != true || !client.bits[4] {
+		t.Fatalf("switching on failed: %v", on.Err)
+	}
+
+	end := m.endPulse(on)
+	if end == nil {
+		t.Fatal("pulse doesn't end")
+	}
+
+	_, write := m.Update(end())
+	write()
+	off := p.Listen()().(WriteResultMsg)
+	if off.Err != nil || off.Value != false || off.Pulse != 0 || client.bits[4] {
+		t.Fatalf("switching off failed: %v", off.Err)
+	}
+
+	if !slices.Equal(client.requests, []string{"ReadCoils 4", "WriteCoil 4", "ReadCoils 4", "ReadCoils 4", "WriteCoil 4", "ReadCoils 4"}) {
+		t.Fatalf("unexpected requests %v", client.requests)
+	}
+}
+
+func TestPulseFailed(t *testing.T) {
+	client := newFakeClient()
+	client.err = modbus.ErrIllegalDataAddress
+	p := newPoller(client)
+	defer p.Stop()
+
+	m := NewTable(Theme, p)
+	dp := &Datapoint{SlaveId: 1, Addr: 4, Space: SpaceCoil, Flag: FlagReadWrite, DataType: DataTypeBool}
+
+	// The datapoint isn't switched off if switching it on failed
+	p.Pulse(dp, time.Millisecond)()
+	on := p.Listen()().(WriteResultMsg)
+	if on.Err == nil {
+		t.Fatal("switching on didn't fail")
+	}
+
+	if m.endPulse(on) != nil {
+		t.Fatal("failed pulse ends")
+	}
+
+	if m.Status.Err == nil {
+		t.Fatal("failed pulse isn't reported")
+	}
+}
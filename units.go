@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brutella/modbussy/ui"
+)
+
+// unitResult is the printed result of a probed unit id.
+type unitResult struct {
+	SlaveId  uint8  `json:"slaveId"`
+	Response string `json:"response"`
+}
+
+// unitColumns are the columns of printed unit results.
+var unitColumns = []column{
+	{"slaveId", "SERVER ID"},
+	{"response", "RESPONSE"},
+}
+
+// runUnits probes unit ids and prints the ids of the units which
+// responded. It returns the exit code.
+func runUnits(stg storage, args []string) int {
+	probe := ui.DefaultUnitProbe()
+
+	fs := newFlagSet("units", "")
+	ids := fs.String("range", fmt.Sprintf("%d-%d", probe.First, probe.Last), "Range of server ids to probe (e.g. 1-247)")
+	space := fs.String("space", "hr", "Register space of the probe request; either hr, ir, coil or di")
+	addr := fs.Uint("addr", 0, "Address of the probe request")
+	report := fs.Bool("report-server-id", false, "Probe with Report Server ID requests instead of reading the address")
+	timeout := fs.Duration("probe-timeout", time.Duration(probe.Timeout), "Timeout of a probe request")
+	format := formatFlag(fs)
+	fs.Parse(args)
+
+	first, last, err := parseRange(*ids)
+	if err != nil || first == 0 || last > math.MaxUint8 {
+		logError(fmt.Errorf(`invalid server id range "%s"`, *ids))
+		return 2
+	}
+
+	sp, err := ui.ParseSpace(*space)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if *addr > math.MaxUint16 {
+		logError(fmt.Errorf("invalid address %d", *addr))
+		return 2
+	}
+
+	if err := checkFormat(*format); err != nil {
+		logError(err)
+		return 2
+	}
+
+	probe.First = uint8(first)
+	probe.Last = uint8(last)
+	probe.Space = sp
+	probe.Addr = uint16(*addr)
+	probe.ReportServerId = *report
+	probe.Timeout = ui.Duration(*timeout)
+	probe.Retries = stg.Modbus.Retries
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var results []unitResult
+	var rows [][]string
+	missing := 0
+	err = ui.ProbeUnits(ctx, *stg.Modbus.ClientConfiguration(), probe, func(res ui.UnitResult) {
+		if res.Missing() {
+			missing++
+			return
+		}
+
+		r := unitResult{SlaveId: res.SlaveId, Response: res.Response()}
+		results = append(results, r)
+		rows = append(rows, []string{fmt.Sprintf("%d", r.SlaveId), r.Response})
+	})
+	if err != nil {
+		logError(err)
+		return 1
+	}
+
+	if err := printResults(os.Stdout, *format, results, unitColumns, rows); err != nil {
+		logError(err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "%d found, %d not responding\n", len(results), missing)
+
+	if ctx.Err() != nil {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/brutella/modbussy/ui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/simonvetter/modbus"
+)
+
+// runWatch reads the datapoints repeatedly and prints every sample
+// to stdout until it is interrupted. It returns the exit code.
+func runWatch(stg storage, args []string) int {
+	fs := newFlagSet("watch", "[datapoint names...]")
+	format := fs.String("format", "ndjson", "Output format; either ndjson or csv")
+	interval := fs.Duration("interval", time.Duration(stg.Modbus.PollInterval), "Poll interval of datapoints without their own poll interval")
+	dp, adhoc := datapointFlags(fs)
+	fs.Parse(args)
+
+	datapoints, err := selectDatapoints(stg.Datapoints, fs.Args())
+	if err == nil && adhoc() {
+		datapoints, err = adhocDatapoint(dp)
+	}
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if len(datapoints) == 0 {
+		logError(errors.New("no datapoints to watch"))
+		return 2
+	}
+
+	if *interval <= 0 {
+		*interval = time.Second
+	}
+
+	var write func(r result) error
+	switch *format {
+	case "ndjson":
+		enc := json.NewEncoder(os.Stdout)
+		write = func(r result) error {
+			return enc.Encode(r)
+		}
+	case "csv":
+		cw := csv.NewWriter(os.Stdout)
+		header := []string{"time"}
+		for _, c := range resultColumns {
+			header = append(header, c.Key)
+		}
+		cw.Write(header)
+		write = func(r result) error {
+			cw.Write(append([]string{r.Time}, r.strings()...))
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		logError(fmt.Errorf(`invalid format "%s"`, *format))
+		return 2
+	}
+
+	// The default connection is only opened
+	// if datapoints are read with it
+	var client *modbus.ModbusClient
+	if slices.ContainsFunc(datapoints, func(dp *ui.Datapoint) bool { return dp.Connection == "" }) {
+		client, err = connect(stg.Modbus)
+		if err != nil {
+			logError(err)
+			return 1
+		}
+		defer client.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p := ui.NewPoller(client, stg.Modbus.Retries)
+	defer p.Stop()
+
+	for _, c := range stg.profile.Connections {
+		conf := c.Modbus.ClientConfiguration()
+		client, err := modbus.NewClient(conf)
+		if err != nil {
+			logError(fmt.Errorf(`connection "%s": %w`, c.Name, err))
+			return 1
+		}
+		p.Attach(c.Name, client, conf, c.Modbus.Retries)
+	}
+
+	ui.Watch(ctx, p, datapoints, *interval, stg.Modbus.MaxGap, func(msg tea.Msg) {
+		switch msg := msg.(type) {
+		case ui.ReadResultMsg:
+			r := newResult(msg.Datapoint)
+			r.Time = time.Now().Format(time.RFC3339Nano)
+			if err := write(r); err != nil {
+				logError(err)
+				stop()
+			}
+		case ui.LinkStateMsg:
+			prefix := ""
+			if msg.Connection != "" {
+				prefix = msg.Connection + ": "
+			}
+
+			switch msg.State {
+			case ui.LinkReconnecting:
+				logError(fmt.Errorf("%sreconnecting (%d): %w", prefix, msg.Attempt, msg.Err))
+			case ui.LinkConnected:
+				fmt.Fprintf(os.Stderr, "%sconnected\n", prefix)
+			}
+		}
+	})
+
+	return 0
+}
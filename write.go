@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brutella/modbussy/ui"
+)
+
+// runWrite writes a value to a datapoint of the database,
+// or to an ad-hoc datapoint. It returns the exit code.
+func runWrite(stg storage, args []string) int {
+	fs := newFlagSet("write", "<datapoint-name|slave:addr:type[:space]> <value>")
+	verify := fs.Bool("verify", false, "Fail if the value read back after writing differs")
+	raw := fs.Bool("raw", false, "Write the raw value instead of the scaled value")
+	order := fs.String("order", "ABCD", "Byte order of an ad-hoc datapoint; either ABCD, DCBA, BADC or CDAB")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	if stg.readOnly {
+		logError(errors.New("writing is disabled in read-only mode"))
+		return 2
+	}
+
+	dp, err := parseTarget(stg.Datapoints, fs.Arg(0), *order)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if err := ui.WriteError(dp); err != nil {
+		logError(err)
+		return 2
+	}
+
+	// Values can be specified by their label,
+	// which maps to a raw value
+	arg := fs.Arg(1)
+	parse := dp.ParseScaledValue
+	if v, ok := dp.LabelValue(arg); ok {
+		arg = v
+		parse = dp.ParseValue
+	}
+	if *raw {
+		parse = dp.ParseValue
+	}
+
+	val, err := parse(arg)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	if err := dp.CheckLimits(val); err != nil {
+		logError(err)
+		return 2
+	}
+
+	cfg, err := connection(stg, dp.Connection)
+	if err != nil {
+		logError(err)
+		return 2
+	}
+
+	client, close, err := dial(cfg)
+	if err != nil {
+		logError(err)
+		return 1
+	}
+	defer close()
+
+	// The old value is logged in the write history
+	ui.ReadDatapoints(client, []*ui.Datapoint{dp}, 0)
+	old := dp.Value
+
+	err = ui.WriteDatapoint(client, dp, val)
+
+	// Devices may silently clamp values, which is
+	// detected by reading back the written value
+	var readBack any
+	var verr error
+	if err == nil {
+		verr = ui.VerifyDatapoint(client, dp, val)
+		readBack = dp.Value
+	}
+
+	entryErr := err
+	if err == nil && dp.Err != nil {
+		entryErr = fmt.Errorf("reading back failed: %w", dp.Err)
+	}
+	if err := stg.history.Append(ui.NewHistoryEntry(dp, old, val, readBack, entryErr)); err != nil {
+		logError(fmt.Errorf("logging the write failed: %w", err))
+	}
+
+	if err != nil {
+		if ui.IsException(err) {
+			err = fmt.Errorf("device returned exception: %w", err)
+		}
+		logError(fmt.Errorf("writing %v failed: %w", val, err))
+		return 1
+	}
+
+	if verr != nil {
+		if *verify {
+			logError(fmt.Errorf("verification failed: %w", verr))
+			return 1
+		}
+		logError(verr)
+	}
+
+	return 0
+}
+
+// parseTarget returns the datapoint with the name, or an ad-hoc
+// datapoint specified as slave:addr:type[:space] (e.g. 1:100:int16:hr).
+func parseTarget(datapoints []*ui.Datapoint, target string, order string) (*ui.Datapoint, error) {
+	if dp := findDatapoint(datapoints, target); dp != nil {
+		return dp, nil
+	}
+
+	parts := strings.Split(target, ":")
+	if len(parts) < 3 || len(parts) > 4 {
+		return nil, fmt.Errorf(`datapoint "%s" not found`, target)
+	}
+
+	slave, err := strconv.ParseUint(parts[0], 0, 8)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid server id "%s"`, parts[0])
+	}
+
+	addr, err := strconv.ParseUint(parts[1], 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid address "%s"`, parts[1])
+	}
+
+	space := "hr"
+	if len(parts) == 4 {
+		space = parts[3]
+	}
+
+	dp, err := newDatapoint(uint(slave), int(addr), parts[2], space, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return dp, nil
+}